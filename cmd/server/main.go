@@ -1,82 +1,375 @@
-package main
-
-import (
-	"context"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
-	"github.com/rakheshkrishna2005/url-shortener/internal/api"
-	"github.com/rakheshkrishna2005/url-shortener/internal/api/handlers"
-	"github.com/rakheshkrishna2005/url-shortener/internal/config"
-	"github.com/rakheshkrishna2005/url-shortener/internal/repository/postgres"
-	"github.com/rakheshkrishna2005/url-shortener/internal/service"
-)
-
-func main() {
-	// Load configuration
-	cfg := config.New()
-	
-	// Connect to database
-	db, err := sqlx.Connect("postgres", cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
-	
-	// Set up connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	
-	// Create repositories
-	urlRepo := postgres.NewURLRepository(db)
-	
-	// Create services
-	urlService := service.NewURLService(urlRepo, cfg)
-	
-	// Create handlers
-	urlHandler := handlers.NewURLHandler(urlService)
-	healthHandler := handlers.NewHealthHandler()
-	
-	// Set up router
-	router := api.NewRouter(urlHandler, healthHandler)
-	
-	// Create HTTP server
-	server := &http.Server{
-		Addr:         ":" + cfg.ServerPort,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-	
-	// Start server in a goroutine
-	go func() {
-		log.Printf("Server starting on port %s", cfg.ServerPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
-		}
-	}()
-	
-	// Set up graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	
-	log.Println("Server shutting down...")
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
-	
-	log.Println("Server exited properly")
-}
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/handlers"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/middleware"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/render"
+	"github.com/rakheshkrishna2005/url-shortener/internal/assets"
+	"github.com/rakheshkrishna2005/url-shortener/internal/certs"
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/linkcheck"
+	"github.com/rakheshkrishna2005/url-shortener/internal/metadata"
+	"github.com/rakheshkrishna2005/url-shortener/internal/metrics"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/ownership"
+	"github.com/rakheshkrishna2005/url-shortener/internal/policy"
+	"github.com/rakheshkrishna2005/url-shortener/internal/ratelimit"
+	"github.com/rakheshkrishna2005/url-shortener/internal/repository/postgres"
+	"github.com/rakheshkrishna2005/url-shortener/internal/server"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+	"github.com/rakheshkrishna2005/url-shortener/internal/shortcode"
+	"github.com/rakheshkrishna2005/url-shortener/internal/sso"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+	"github.com/rakheshkrishna2005/url-shortener/web"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.New()
+
+	// Connect to database
+	db, err := sqlx.Connect("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Set up connection pool
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	// Characters short codes are made of
+	alphabet, err := utils.NewAlphabet(cfg.ShortCodeAlphabet)
+	if err != nil {
+		log.Fatalf("Invalid short code alphabet: %v", err)
+	}
+
+	// Create repositories
+	urlRepo := postgres.NewURLRepository(db, alphabet)
+
+	// Workspaces and short domains, which the destination policy also consults
+	ownershipVerifier := ownership.NewVerifier(ownership.Options{AllowPrivate: !cfg.BlockPrivateIPs})
+	workspaceService := service.NewWorkspaceService(postgres.NewWorkspaceRepository(db), cfg)
+	domainService := service.NewDomainService(postgres.NewDomainRepository(db), ownershipVerifier, workspaceService, cfg)
+
+	// Load destination policy
+	var threatList *policy.ThreatList
+	if cfg.ThreatListPath != "" {
+		threatList, err = policy.LoadThreatList(cfg.ThreatListPath)
+		if err != nil {
+			log.Fatalf("Failed to load threat list: %v", err)
+		}
+		log.Printf("Loaded %d threat list prefixes", threatList.Len())
+	}
+	destinationPolicy := policy.NewEngine(policy.Options{
+		AllowDomains:    cfg.AllowDomains,
+		DenyDomains:     cfg.DenyDomains,
+		BlockPrivateIPs: cfg.BlockPrivateIPs,
+		OwnHosts:        ownHosts(cfg.BaseURL),
+		Domains:         domainService,
+		ThreatList:      threatList,
+	})
+
+	// Pick how generated short codes are allocated
+	codeGenerator, err := shortcode.New(cfg.ShortCodeStrategy, shortcode.Options{
+		Alphabet:  alphabet,
+		Length:    cfg.ShortCodeLen,
+		Secret:    cfg.ShortCodeSecret,
+		BlockSize: cfg.ShortCodeBlockSize,
+
+		GrowCollisionRate: cfg.ShortCodeGrowCollisionRate,
+		GrowOccupancy:     cfg.ShortCodeGrowOccupancy,
+	}, urlRepo)
+	if err != nil {
+		log.Fatalf("Failed to set up short code generation: %v", err)
+	}
+	if cfg.ShortCodeStrategy != shortcode.StrategyRandom && cfg.ShortCodeSecret == "" {
+		log.Printf("SHORT_CODE_SECRET is empty; counter-based codes can be decoded to their sequence number")
+	}
+
+	// Keep offensive words and our own routes out of codes and aliases
+	blocklist := shortcode.DefaultBlocklist
+	if cfg.CodeBlocklistPath != "" {
+		blocklist, err = shortcode.LoadBlocklist(cfg.CodeBlocklistPath)
+		if err != nil {
+			log.Fatalf("Failed to load code blocklist: %v", err)
+		}
+	}
+	codePolicy := shortcode.NewPolicy(blocklist, slices.Concat(api.ReservedPaths, cfg.ReservedAliases))
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// Keep short code occupancy current so random codes grow before they saturate,
+	// starting from the length they had reached before a restart
+	if err := shortcode.LoadOccupancy(jobsCtx, codeGenerator, urlRepo); err != nil {
+		log.Printf("Failed to count short codes: %v", err)
+	}
+	go shortcode.WatchOccupancy(jobsCtx, codeGenerator, urlRepo, cfg.ShortCodeStatsInterval)
+
+	// Dependencies that must be healthy before the service takes traffic
+	readinessChecks := []handlers.ReadinessCheck{
+		{Name: "database", Check: db.PingContext},
+	}
+
+	// Fetch destination metadata in the background
+	var metadataScheduler service.MetadataScheduler
+	if cfg.MetadataEnabled {
+		fetcher := metadata.NewFetcher(metadata.Options{
+			Timeout:  cfg.MetadataTimeout,
+			MaxBytes: cfg.MetadataMaxBytes,
+		})
+		metadataWorker := metadata.NewWorker(fetcher, urlRepo, cfg.MetadataWorkers, 100)
+		go metadataWorker.Run(jobsCtx)
+		metadataScheduler = metadataWorker
+		readinessChecks = append(readinessChecks, handlers.ReadinessCheck{Name: "metadata_queue", Check: metadataWorker.Check})
+	}
+
+	// Periodically check destinations for broken links
+	if cfg.LinkCheckEnabled {
+		var notifier linkcheck.Notifier
+		if cfg.LinkCheckWebhookURL != "" {
+			notifier = linkcheck.NewWebhookNotifier(cfg.LinkCheckWebhookURL)
+		}
+		checker := linkcheck.NewChecker(urlRepo, notifier, linkcheck.Options{
+			Interval:         cfg.LinkCheckInterval,
+			Concurrency:      cfg.LinkCheckConcurrency,
+			PerHostLimit:     cfg.LinkCheckPerHost,
+			PoliteDelay:      cfg.LinkCheckDelay,
+			FailureThreshold: cfg.LinkCheckFailureThreshold,
+		})
+		go checker.Run(jobsCtx)
+	}
+
+	// Create services
+	usageService := service.NewUsageService(postgres.NewUsageRepository(db), workspaceService, cfg)
+	urlService := service.NewURLService(urlRepo, destinationPolicy, codeGenerator, codePolicy, alphabet, domainService, workspaceService, usageService, metadataScheduler, cfg)
+	go urlService.RunTrashPurge(jobsCtx, cfg.TrashPurgeInterval)
+	// Single sign-on with an OpenID Connect provider
+	var ssoProvider *sso.Provider
+	if cfg.OIDCIssuerURL != "" {
+		roleValues, err := sso.ParseRoleMap(cfg.OIDCRoleMap)
+		if err != nil {
+			log.Fatalf("Invalid OIDC_ROLE_MAP: %v", err)
+		}
+		defaultRole := models.Role(cfg.OIDCDefaultRole)
+		if defaultRole != "" && !defaultRole.Valid() {
+			log.Fatalf("Invalid OIDC_DEFAULT_ROLE %q: want user, admin or empty", cfg.OIDCDefaultRole)
+		}
+		ssoProvider = sso.New(sso.Options{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			APIAudience:  cfg.OIDCAPIAudience,
+			Roles: sso.RoleMapping{
+				Claim:   cfg.OIDCRoleClaim,
+				Values:  roleValues,
+				Default: defaultRole,
+			},
+		})
+		log.Printf("Single sign-on enabled with %s", cfg.OIDCIssuerURL)
+	}
+
+	var identities service.IdentityVerifier
+	if ssoProvider != nil {
+		identities = ssoProvider
+	}
+	userService, err := service.NewUserService(postgres.NewUserRepository(db), identities, cfg)
+	if err != nil {
+		log.Fatalf("Failed to create user service: %v", err)
+	}
+	go userService.RunSessionCleanup(jobsCtx, cfg.SessionCleanupInterval)
+
+	// Web pages and static files come from the binary, or from disk while developing
+	templatesFS, staticFS := web.Templates(), web.Static()
+	var staticAssets *assets.Assets
+	if cfg.WebDevDir != "" {
+		templatesFS = os.DirFS(filepath.Join(cfg.WebDevDir, "templates"))
+		staticAssets = assets.NewDev(os.DirFS(filepath.Join(cfg.WebDevDir, "static")), "/static/")
+		log.Printf("Serving web files from %s", cfg.WebDevDir)
+	} else if staticAssets, err = assets.New(staticFS, "/static/"); err != nil {
+		log.Fatalf("Failed to load static assets: %v", err)
+	}
+
+	// Parse page templates once, letting deployments override them
+	templateFuncs := template.FuncMap{"asset": staticAssets.Path}
+	renderer, err := render.New(templatesFS, cfg.TemplateOverrideDir, templateFuncs, cfg.WebDevDir != "")
+	if err != nil {
+		log.Fatalf("Failed to load templates: %v", err)
+	}
+
+	// Limit redirects per client IP per minute
+	var redirectLimiter *ratelimit.Limiter
+	if cfg.RedirectRateLimit > 0 {
+		redirectLimiter = ratelimit.New(cfg.RedirectRateLimit, time.Minute)
+	}
+
+	// Create handlers
+	urlHandler := handlers.NewURLHandler(urlService, workspaceService, renderer, redirectLimiter)
+	domainHandler := handlers.NewDomainHandler(domainService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+	usageHandler := handlers.NewUsageHandler(usageService)
+	auditHandler := handlers.NewAuditHandler(service.NewAuditService(postgres.NewAuditRepository(db)))
+	authHandler := handlers.NewAuthHandler(userService, ssoProvider, cfg.OIDCProviderName, renderer)
+	healthHandler := handlers.NewHealthHandler(codeGenerator, readinessChecks, cfg.ReadinessTimeout)
+
+	// Export metrics
+	var metricsHandler http.Handler
+	if cfg.MetricsEnabled {
+		registry := metrics.NewRegistry()
+		shortcode.RegisterMetrics(registry, codeGenerator)
+		metricsHandler = registry
+	}
+
+	// Operational endpoints move to their own listener when ADMIN_ADDR is set
+	publicMetrics := metricsHandler
+	if cfg.AdminAddr != "" {
+		publicMetrics = nil
+	}
+
+	// Set up router
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	clientIP := middleware.ClientIP(trustedProxies)
+	sessions := middleware.Sessions(userService, cfg.SessionCookieSecure)
+	var bearerAuth func(http.Handler) http.Handler
+	if ssoProvider != nil {
+		bearerAuth = middleware.BearerTokens(userService)
+	}
+	router := api.NewRouter(urlHandler, domainHandler, workspaceHandler, usageHandler, auditHandler, authHandler, clientIP, sessions, bearerAuth, healthHandler, publicMetrics, staticAssets, cfg.AdminToken)
+
+	// Create HTTP server
+	serverOpts := server.Options{
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		HTTP2:             cfg.HTTP2Enabled,
+		H2C:               cfg.H2CEnabled,
+	}
+	httpServer := server.New(router, serverOpts)
+	servers := []*http.Server{httpServer}
+
+	// Serve HTTPS from a certificate on disk or with ACME certificates
+	var tlsConfig *tls.Config
+	switch {
+	case cfg.TLSCertFile != "" && cfg.ACMEEnabled:
+		log.Fatalf("TLS_CERT_FILE and ACME_ENABLED can't be used together")
+	case cfg.TLSCertFile != "":
+		certReloader, err := server.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		go certReloader.Watch(jobsCtx, cfg.TLSReloadInterval)
+		tlsConfig = &tls.Config{GetCertificate: certReloader.GetCertificate}
+	case cfg.ACMEEnabled:
+		certManager, err := certs.NewManager(certs.Options{
+			Email:        cfg.ACMEEmail,
+			DirectoryURL: cfg.ACMEDirectoryURL,
+			CAFile:       cfg.ACMECAFile,
+		}, postgres.NewCertCache(db), domainService.AllowCertificate)
+		if err != nil {
+			log.Fatalf("Failed to set up ACME: %v", err)
+		}
+
+		// Plain HTTP keeps working and answers HTTP-01 challenges
+		httpServer.Handler = certManager.HTTPHandler(router)
+		tlsConfig = certManager.TLSConfig()
+	}
+	if tlsConfig != nil {
+		tlsServer := server.New(router, serverOpts)
+		tlsServer.TLSConfig = tlsConfig
+		servers = append(servers, tlsServer)
+		go serve(tlsServer, ":"+cfg.TLSPort, true)
+	}
+
+	// Start server in a goroutine
+	serverAddr := cfg.ServerAddr
+	if serverAddr == "" {
+		serverAddr = ":" + cfg.ServerPort
+	}
+	go serve(httpServer, serverAddr, false)
+
+	// Health, metrics and pprof for operators only
+	if cfg.AdminAddr != "" {
+		adminServer := server.New(server.AdminHandler(healthHandler, metricsHandler), server.Options{
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		})
+		servers = append(servers, adminServer)
+		go serve(adminServer, cfg.AdminAddr, false)
+	}
+
+	// Set up graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Server shutting down...")
+
+	// Fail readiness first so load balancers stop routing here while requests still succeed
+	healthHandler.ShutDown()
+	time.Sleep(cfg.ShutdownDrainDelay)
+	stopJobs()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatalf("Server forced to shutdown: %v", err)
+		}
+	}
+
+	log.Println("Server exited properly")
+}
+
+// ownHosts returns the hosts this instance answers on, so links can't point back at it
+func ownHosts(baseURL string) []string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	return []string{u.Host}
+}
+
+// serve accepts connections on addr, a host:port or unix:/path, until the server is shut down
+func serve(srv *http.Server, addr string, useTLS bool) {
+	ln, err := server.Listen(addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", addr, err)
+	}
+
+	log.Printf("Server listening on %s", addr)
+	if useTLS {
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Server on %s failed: %v", addr, err)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// ListAliases handles GET requests for a URL's aliases
+func (h *URLHandler) ListAliases(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	aliases, err := h.urlService.ListAliases(r.Context(), id, actorFor(r))
+	if err != nil {
+		aliasError(w, "Failed to list aliases", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aliases)
+}
+
+// AddAlias handles POST requests adding an alias to a URL
+func (h *URLHandler) AddAlias(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.urlService.AddAlias(r.Context(), id, req.Alias, actorFor(r)); err != nil {
+		aliasError(w, "Failed to add alias", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// RenameAlias handles PUT requests replacing one of a URL's aliases
+func (h *URLHandler) RenameAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.urlService.RenameAlias(r.Context(), id, vars["alias"], req.Alias, actorFor(r)); err != nil {
+		aliasError(w, "Failed to rename alias", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveAlias handles DELETE requests for one of a URL's aliases
+func (h *URLHandler) RemoveAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.urlService.RemoveAlias(r.Context(), id, vars["alias"], actorFor(r)); err != nil {
+		aliasError(w, "Failed to remove alias", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// aliasError writes the response for a failed alias operation
+func aliasError(w http.ResponseWriter, prefix string, err error) {
+	if accessError(w, err) {
+		return
+	}
+	switch err {
+	case models.ErrURLNotFound:
+		http.Error(w, "URL not found", http.StatusNotFound)
+	case models.ErrAliasNotFound:
+		http.Error(w, "Alias not found", http.StatusNotFound)
+	case models.ErrDuplicateAlias:
+		http.Error(w, "Custom alias already exists", http.StatusConflict)
+	case models.ErrInvalidAlias:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case models.ErrAliasReserved, models.ErrAliasBlocked:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, prefix+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+)
+
+// AuditHandler handles audit log requests
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLog handles GET requests for the audit log, filtered by link, actor, action,
+// request ID and time range
+func (h *AuditHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		if err == models.ErrInvalidAuditAction || err == models.ErrInvalidAuditActorType {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to list audit log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// auditFilter parses the audit log query parameters. Times are RFC 3339.
+func auditFilter(query url.Values) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		ActorType: models.AuditActorType(query.Get("actor_type")),
+		Action:    models.AuditAction(query.Get("action")),
+		RequestID: query.Get("request_id"),
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	var err error
+	if v := query.Get("url_id"); v != "" {
+		if filter.URLID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, errors.New("invalid url_id")
+		}
+	}
+	if v := query.Get("actor_id"); v != "" {
+		if filter.ActorID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, errors.New("invalid actor_id")
+		}
+	}
+	if v := query.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("invalid since: must be an RFC 3339 time")
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("invalid until: must be an RFC 3339 time")
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/middleware"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/render"
+	"github.com/rakheshkrishna2005/url-shortener/internal/auth"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+	"github.com/rakheshkrishna2005/url-shortener/internal/sso"
+)
+
+// ssoCookie carries a single sign-on attempt's state, nonce and PKCE verifier to the callback
+const ssoCookie = "ziply_sso"
+
+// ssoCookiePath limits the single sign-on cookie to the login routes
+const ssoCookiePath = "/login/sso"
+
+// AuthHandler handles signing up, in and out of the web UI
+type AuthHandler struct {
+	userService *service.UserService
+	sso         *sso.Provider
+	ssoName     string
+	renderer    *render.Renderer
+}
+
+// NewAuthHandler creates a new AuthHandler. A nil provider turns single sign-on off;
+// providerName is what the login page calls it.
+func NewAuthHandler(userService *service.UserService, provider *sso.Provider, providerName string, renderer *render.Renderer) *AuthHandler {
+	return &AuthHandler{
+		userService: userService,
+		sso:         provider,
+		ssoName:     providerName,
+		renderer:    renderer,
+	}
+}
+
+// viewer is who is looking at a page, for the navigation and forms
+type viewer struct {
+	User      *models.User
+	CSRFToken string
+}
+
+// newViewer describes the caller of a page request
+func newViewer(w http.ResponseWriter, r *http.Request) viewer {
+	return viewer{
+		User:      middleware.CurrentUser(r),
+		CSRFToken: middleware.CSRFToken(w, r),
+	}
+}
+
+// authPage is the data of the login and register pages
+type authPage struct {
+	Viewer              viewer
+	Email               string
+	Error               string
+	RegistrationEnabled bool
+	// SSOName names the single sign-on provider; empty when single sign-on is off
+	SSOName string
+}
+
+// LoginPage serves the sign-in form
+func (h *AuthHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	h.renderAuthPage(w, r, http.StatusOK, "login.html", "", "")
+}
+
+// Login handles the sign-in form
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	creds := models.Credentials{
+		Email:    r.PostFormValue("email"),
+		Password: r.PostFormValue("password"),
+	}
+
+	token, session, err := h.userService.Login(r.Context(), creds)
+	if err == models.ErrInvalidCredentials {
+		h.renderAuthPage(w, r, http.StatusUnauthorized, "login.html", creds.Email, "Incorrect email or password.")
+		return
+	} else if err != nil {
+		log.Printf("Failed to sign in: %v", err)
+		h.renderAuthPage(w, r, http.StatusInternalServerError, "login.html", creds.Email, "We couldn't sign you in right now. Please try again later.")
+		return
+	}
+
+	h.startSession(w, r, token, session)
+}
+
+// RegisterPage serves the sign-up form
+func (h *AuthHandler) RegisterPage(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if !h.userService.RegistrationEnabled() {
+		status = http.StatusForbidden
+	}
+	h.renderAuthPage(w, r, status, "register.html", "", "")
+}
+
+// Register handles the sign-up form
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	creds := models.Credentials{
+		Email:    r.PostFormValue("email"),
+		Password: r.PostFormValue("password"),
+	}
+	if creds.Password != r.PostFormValue("password_confirm") {
+		h.renderAuthPage(w, r, http.StatusBadRequest, "register.html", creds.Email, "Passwords don't match.")
+		return
+	}
+
+	token, session, err := h.userService.Register(r.Context(), creds)
+	if err != nil {
+		status := http.StatusBadRequest
+		message := err.Error()
+		switch err {
+		case models.ErrDuplicateEmail:
+			status = http.StatusConflict
+		case models.ErrRegistrationClosed:
+			status = http.StatusForbidden
+		case models.ErrInvalidEmail, models.ErrWeakPassword:
+		default:
+			log.Printf("Failed to register: %v", err)
+			status = http.StatusInternalServerError
+			message = "We couldn't create your account right now. Please try again later."
+		}
+		h.renderAuthPage(w, r, status, "register.html", creds.Email, message)
+		return
+	}
+
+	h.startSession(w, r, token, session)
+}
+
+// SSOLogin sends the browser to the identity provider
+func (h *AuthHandler) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		token, err := auth.NewToken()
+		if err != nil {
+			http.Error(w, "Failed to start single sign-on", http.StatusInternalServerError)
+			return
+		}
+		values[i] = token
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := h.sso.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Failed to start single sign-on: %v", err)
+		h.renderAuthPage(w, r, http.StatusServiceUnavailable, "login.html", "", "Single sign-on is unavailable right now. Please try again later.")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Value:    strings.Join(values[:], "."),
+		Path:     ssoCookiePath,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   middleware.SecureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// SSOCallback completes single sign-on when the identity provider sends the browser back
+func (h *AuthHandler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The attempt can only be completed once
+	cookie, err := r.Cookie(ssoCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Path:     ssoCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   middleware.SecureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		log.Printf("Identity provider returned an error: %s: %s", errCode, query.Get("error_description"))
+		h.renderAuthPage(w, r, http.StatusUnauthorized, "login.html", "", "Single sign-on was cancelled or refused.")
+		return
+	}
+
+	var parts []string
+	if err == nil {
+		parts = strings.Split(cookie.Value, ".")
+	}
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(query.Get("state"))) != 1 {
+		h.renderAuthPage(w, r, http.StatusBadRequest, "login.html", "", "Your sign-in attempt expired. Please try again.")
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+
+	identity, err := h.sso.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+	if err == sso.ErrNoRole {
+		h.renderAuthPage(w, r, http.StatusForbidden, "login.html", "", "Your account isn't allowed to use this service.")
+		return
+	} else if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		h.renderAuthPage(w, r, http.StatusUnauthorized, "login.html", "", "Single sign-on failed. Please try again.")
+		return
+	}
+
+	token, session, err := h.userService.LoginIdentity(r.Context(), identity)
+	if err == models.ErrIdentityIncomplete || err == models.ErrIdentityConflict {
+		h.renderAuthPage(w, r, http.StatusForbidden, "login.html", identity.Email, err.Error()+".")
+		return
+	} else if err != nil {
+		log.Printf("Failed to sign in identity %s: %v", identity.Subject, err)
+		h.renderAuthPage(w, r, http.StatusInternalServerError, "login.html", "", "We couldn't sign you in right now. Please try again later.")
+		return
+	}
+
+	h.startSession(w, r, token, session)
+}
+
+// Logout ends the caller's session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if token := middleware.SessionToken(r); token != "" {
+		if err := h.userService.Logout(r.Context(), token); err != nil {
+			log.Printf("Failed to sign out: %v", err)
+		}
+	}
+	middleware.ClearSessionCookie(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// startSession hands a new session to the browser and sends it to the dashboard
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, token string, session *models.Session) {
+	middleware.SetSessionCookie(w, r, token, session.ExpiresAt)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// renderAuthPage renders the login or register page with an optional error
+func (h *AuthHandler) renderAuthPage(w http.ResponseWriter, r *http.Request, status int, name, email, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	h.renderer.HTML(w, status, name, authPage{
+		Viewer:              newViewer(w, r),
+		Email:               email,
+		Error:               message,
+		RegistrationEnabled: h.userService.RegistrationEnabled(),
+		SSOName:             h.ssoProviderName(),
+	})
+}
+
+// ssoProviderName returns the name shown on the single sign-on button, or "" when it is off
+func (h *AuthHandler) ssoProviderName() string {
+	if h.sso == nil {
+		return ""
+	}
+	if h.ssoName == "" {
+		return "single sign-on"
+	}
+	return h.ssoName
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/render"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// dashboardPageSize is the number of links shown per dashboard page
+const dashboardPageSize = 20
+
+// dashboardLink is a link as shown on the dashboard
+type dashboardLink struct {
+	*models.URLDetailResponse
+	Chart      []chartBar
+	MaxClicks  int
+	Expired    bool
+	AliasValue string
+}
+
+// chartBar is one day in a link's click chart
+type chartBar struct {
+	Day    time.Time
+	Clicks int
+	// Height is the bar height as a percentage of the busiest day
+	Height int
+}
+
+// Home serves the landing page. Signed-in users can shorten into the workspaces they edit links in.
+func (h *URLHandler) Home(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var workspaces []*models.Workspace
+	if actor := actorFor(r); actor.User != nil {
+		all, err := h.workspaceService.ListWorkspaces(r.Context(), actor)
+		if err != nil {
+			log.Printf("Failed to list workspaces: %v", err)
+		}
+		for _, ws := range all {
+			if ws.Role.Can(models.PermEditLinks) {
+				workspaces = append(workspaces, ws)
+			}
+		}
+	}
+
+	data := struct {
+		Viewer     viewer
+		Workspaces []*models.Workspace
+	}{newViewer(w, r), workspaces}
+	h.renderer.HTML(w, http.StatusOK, "index.html", data)
+}
+
+// Dashboard renders the caller's links with their stats and forms to manage them.
+// Signed-in users see the links they created, or those of a workspace they belong to;
+// anonymous visitors are asked to log in. An invitation query parameter offers to join
+// the workspace it is for.
+func (h *URLHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	actor := actorFor(r)
+	filter, err := ownURLsFilter(r)
+	if err != nil {
+		h.renderer.Error(w, r, render.ErrorPage{
+			StatusCode: http.StatusBadRequest,
+			Heading:    "Workspace not found",
+			Message:    "That workspace link isn't valid.",
+		})
+		return
+	}
+	filter.Limit = dashboardPageSize
+	filter.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	resp := &models.URLListResponse{Limit: filter.Limit}
+	if actor.User != nil || filter.WorkspaceID != 0 {
+		resp, err = h.urlService.ListURLs(r.Context(), filter, actor)
+	}
+	if err == models.ErrWorkspaceNotFound || err == models.ErrSignInRequired {
+		h.renderer.Error(w, r, render.ErrorPage{
+			StatusCode: http.StatusNotFound,
+			Heading:    "Workspace not found",
+			Message:    "This workspace doesn't exist or you aren't a member of it.",
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list dashboard links: %v", err)
+		h.renderer.Error(w, r, render.ErrorPage{
+			StatusCode: http.StatusInternalServerError,
+			Heading:    "Something went wrong",
+			Message:    "We couldn't load your links right now. Please try again later.",
+		})
+		return
+	}
+
+	links := make([]dashboardLink, len(resp.URLs))
+	for i, item := range resp.URLs {
+		links[i] = newDashboardLink(item)
+	}
+
+	// Workspaces are listed as tabs; viewers of the current one can't change its links
+	var workspaces []*models.Workspace
+	var current *models.Workspace
+	if actor.User != nil {
+		if workspaces, err = h.workspaceService.ListWorkspaces(r.Context(), actor); err != nil {
+			log.Printf("Failed to list dashboard workspaces: %v", err)
+		}
+	}
+	for _, ws := range workspaces {
+		if ws.ID == filter.WorkspaceID {
+			current = ws
+		}
+	}
+	canEdit := current == nil || current.Role.Can(models.PermEditLinks) || actor.Admin
+
+	data := struct {
+		Viewer     viewer
+		Links      []dashboardLink
+		Workspaces []*models.Workspace
+		Workspace  *models.Workspace
+		CanEdit    bool
+		Invitation string
+		// WorkspaceID is the workspace shown, or zero for personal links
+		WorkspaceID int64
+		PrevOffset  int
+		NextOffset  int
+		HasPrev     bool
+		HasNext     bool
+	}{
+		Viewer:      newViewer(w, r),
+		Links:       links,
+		Workspaces:  workspaces,
+		Workspace:   current,
+		CanEdit:     canEdit,
+		Invitation:  r.URL.Query().Get("invitation"),
+		WorkspaceID: filter.WorkspaceID,
+		PrevOffset:  max(resp.Offset-resp.Limit, 0),
+		NextOffset:  resp.Offset + resp.Limit,
+		HasPrev:     resp.Offset > 0,
+		HasNext:     len(resp.URLs) == resp.Limit,
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.renderer.HTML(w, http.StatusOK, "dashboard.html", data)
+}
+
+// newDashboardLink scales a link's daily clicks into chart bars
+func newDashboardLink(item *models.URLDetailResponse) dashboardLink {
+	link := dashboardLink{URLDetailResponse: item}
+	if item.URL.ExpiresAt != nil {
+		link.Expired = item.URL.ExpiresAt.Before(time.Now())
+	}
+	if item.URL.CustomAlias != nil {
+		link.AliasValue = *item.URL.CustomAlias
+	}
+	if item.Stats == nil {
+		return link
+	}
+
+	for _, d := range item.Stats.Daily {
+		link.MaxClicks = max(link.MaxClicks, d.Clicks)
+	}
+	link.Chart = make([]chartBar, len(item.Stats.Daily))
+	for i, d := range item.Stats.Daily {
+		bar := chartBar{Day: d.Day, Clicks: d.Clicks}
+		if link.MaxClicks > 0 {
+			bar.Height = d.Clicks * 100 / link.MaxClicks
+		}
+		link.Chart[i] = bar
+	}
+	return link
+}
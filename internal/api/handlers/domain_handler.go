@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+)
+
+// DomainHandler handles short domain requests
+type DomainHandler struct {
+	domainService *service.DomainService
+}
+
+// NewDomainHandler creates a new DomainHandler
+func NewDomainHandler(domainService *service.DomainService) *DomainHandler {
+	return &DomainHandler{
+		domainService: domainService,
+	}
+}
+
+// AvailableDomains handles GET requests for the domains the caller's new links can use;
+// workspace_id lists those for links in a workspace
+func (h *DomainHandler) AvailableDomains(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := workspaceParam(r)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	domains, err := h.domainService.AvailableDomains(r.Context(), actorFor(r), workspaceID)
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to list domains: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domains)
+}
+
+// ListDomains handles GET requests for all registered domains
+func (h *DomainHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.domainService.ListDomains(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list domains: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domains)
+}
+
+// CreateDomain handles POST requests registering a domain
+func (h *DomainHandler) CreateDomain(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	domain, err := h.domainService.CreateDomain(r.Context(), req)
+	if err != nil {
+		if err == models.ErrInvalidDomain {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == models.ErrDuplicateDomain {
+			http.Error(w, "Domain already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(domain)
+}
+
+// RegisterDomain handles POST requests from signed-in customers bringing their own domain
+func (h *DomainHandler) RegisterDomain(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	registration, err := h.domainService.RegisterDomain(r.Context(), req, actorFor(r))
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		if err == models.ErrInvalidDomain {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == models.ErrDuplicateDomain {
+			http.Error(w, "Domain already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to register domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(registration)
+}
+
+// VerifyDomain handles POST requests checking a registered domain's ownership token
+func (h *DomainHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.VerifyDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	domain, err := h.domainService.VerifyDomain(r.Context(), id, req.Method, actorFor(r))
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		if err == models.ErrDomainNotFound {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		} else if err == models.ErrInvalidVerifyMethod {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if errors.Is(err, models.ErrDomainUnverified) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to verify domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain)
+}
+
+// UpdateDomain handles PUT requests enabling or disabling a domain for new links
+func (h *DomainHandler) UpdateDomain(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid domain ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.domainService.SetDomainActive(r.Context(), id, req.Active); err != nil {
+		if err == models.ErrDomainNotFound {
+			http.Error(w, "Domain not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update domain: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,37 +1,162 @@
-package handlers
-
-import (
-	"encoding/json"
-	"net/http"
-	"time"
-)
-
-// HealthHandler handles health check requests
-type HealthHandler struct {
-	startTime time.Time
-}
-
-// NewHealthHandler creates a new HealthHandler
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{
-		startTime: time.Now(),
-	}
-}
-
-// HealthCheck returns the status of the service
-func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	status := struct {
-		Status    string        `json:"status"`
-		Uptime    string        `json:"uptime"`
-		Timestamp time.Time     `json:"timestamp"`
-		Duration  time.Duration `json:"duration_ms"`
-	}{
-		Status:    "ok",
-		Uptime:    time.Since(h.startTime).String(),
-		Timestamp: time.Now(),
-		Duration:  time.Since(time.Now().Add(-1 * time.Millisecond)),
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
-}
\ No newline at end of file
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/shortcode"
+	"github.com/rakheshkrishna2005/url-shortener/internal/version"
+)
+
+// CodeStatsSource reports short code generation statistics
+type CodeStatsSource interface {
+	Stats() shortcode.Stats
+}
+
+// ReadinessCheck is a dependency that must be healthy for the service to take traffic
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ComponentStatus is the result of one readiness check
+type ComponentStatus struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// Health statuses
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	startTime    time.Time
+	codes        CodeStatsSource
+	checks       []ReadinessCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHealthHandler creates a new HealthHandler. Each readiness check gets timeout to finish.
+func NewHealthHandler(codes CodeStatsSource, checks []ReadinessCheck, timeout time.Duration) *HealthHandler {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &HealthHandler{
+		startTime: time.Now(),
+		codes:     codes,
+		checks:    checks,
+		timeout:   timeout,
+	}
+}
+
+// ShutDown makes readiness fail so load balancers stop sending traffic before the listeners close
+func (h *HealthHandler) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+// healthReport is the JSON body of the health endpoints
+type healthReport struct {
+	Status     string                     `json:"status"`
+	Version    string                     `json:"version"`
+	Commit     string                     `json:"commit"`
+	Uptime     string                     `json:"uptime"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Duration   int64                      `json:"duration_ms"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+	ShortCodes *shortcode.Stats           `json:"short_codes,omitempty"`
+}
+
+// Livez reports whether the process is up; it never checks dependencies
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, h.report(StatusOK, time.Now()))
+}
+
+// Readyz reports whether the service can take traffic, with a breakdown per dependency
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, h.readiness(r.Context()))
+}
+
+// HealthCheck returns the readiness report along with short code statistics
+func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness(r.Context())
+	if h.codes != nil {
+		stats := h.codes.Stats()
+		report.ShortCodes = &stats
+	}
+	writeHealth(w, report)
+}
+
+// readiness runs every check concurrently and combines the results
+func (h *HealthHandler) readiness(ctx context.Context) *healthReport {
+	start := time.Now()
+	if h.shuttingDown.Load() {
+		return h.report(StatusShuttingDown, start)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	components := make(map[string]ComponentStatus, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkStart := time.Now()
+			status := ComponentStatus{Status: StatusOK}
+			if err := check.Check(ctx); err != nil {
+				status.Status = StatusUnavailable
+				status.Error = err.Error()
+			}
+			status.Duration = time.Since(checkStart).Milliseconds()
+
+			mu.Lock()
+			components[check.Name] = status
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	report := h.report(StatusOK, start)
+	report.Components = components
+	for _, c := range components {
+		if c.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// report fills in the fields shared by all health responses
+func (h *HealthHandler) report(status string, start time.Time) *healthReport {
+	build := version.Get()
+	return &healthReport{
+		Status:    status,
+		Version:   build.Version,
+		Commit:    build.Commit,
+		Uptime:    time.Since(h.startTime).String(),
+		Timestamp: time.Now(),
+		Duration:  time.Since(start).Milliseconds(),
+	}
+}
+
+// writeHealth writes a health report, with 503 unless it is ok
+func writeHealth(w http.ResponseWriter, report *healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// ListTrash handles GET requests for the caller's deleted links, or a workspace's with workspace_id
+func (h *URLHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ownURLsFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	filter.Deleted = true
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	resp, err := h.urlService.ListURLs(r.Context(), filter, actorFor(r))
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to list deleted URLs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RestoreURL handles POST requests taking a URL out of the trash
+func (h *URLHandler) RestoreURL(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.urlService.RestoreURL(r.Context(), id, actorFor(r))
+	if err != nil {
+		if accessError(w, err) || quotaError(w, err) {
+			return
+		} else if err == models.ErrURLNotFound {
+			http.Error(w, "URL not found in the trash", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to restore URL: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,188 +1,553 @@
-package handlers
-
-import (
-	"encoding/json"
-	"net/http"
-	"strconv"
-	"strings"
-
-	"github.com/gorilla/mux"
-	"github.com/rakheshkrishna2005/url-shortener/internal/models"
-	"github.com/rakheshkrishna2005/url-shortener/internal/service"
-)
-
-// URLHandler handles HTTP requests for URL operations
-type URLHandler struct {
-	urlService *service.URLService
-}
-
-// NewURLHandler creates a new URLHandler
-func NewURLHandler(urlService *service.URLService) *URLHandler {
-	return &URLHandler{
-		urlService: urlService,
-	}
-}
-
-// CreateURL handles POST requests to create a new short URL
-func (h *URLHandler) CreateURL(w http.ResponseWriter, r *http.Request) {
-	var req models.CreateURLRequest
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-
-	// Get user IP
-	userIP := getUserIP(r)
-
-	// Create short URL
-	resp, err := h.urlService.CreateShortURL(r.Context(), req, userIP)
-	if err != nil {
-		if err == models.ErrDuplicateAlias {
-			http.Error(w, "Custom alias already exists", http.StatusConflict)
-			return
-		}
-		http.Error(w, "Failed to create short URL: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
-}
-
-// GetURLByID handles GET requests to retrieve URL details by ID
-func (h *URLHandler) GetURLByID(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
-		return
-	}
-
-	details, err := h.urlService.GetURLByID(r.Context(), id)
-	if err != nil {
-		if err == models.ErrURLNotFound {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to retrieve URL: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(details)
-}
-
-// UpdateURL handles PUT requests to update URL details
-func (h *URLHandler) UpdateURL(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
-		return
-	}
-
-	var req struct {
-		OriginalURL string  `json:"original_url,omitempty"`
-		CustomAlias *string `json:"custom_alias,omitempty"`
-		ExpiresIn   *int    `json:"expires_in,omitempty"`
-	}
-
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-
-	err = h.urlService.UpdateURL(r.Context(), id, req.OriginalURL, req.CustomAlias, req.ExpiresIn)
-	if err != nil {
-		if err == models.ErrURLNotFound {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		} else if err == models.ErrDuplicateAlias {
-			http.Error(w, "Custom alias already exists", http.StatusConflict)
-			return
-		}
-		http.Error(w, "Failed to update URL: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// DeleteURL handles DELETE requests to remove a URL
-func (h *URLHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
-		return
-	}
-
-	err = h.urlService.DeleteURL(r.Context(), id)
-	if err != nil {
-		if err == models.ErrURLNotFound {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to delete URL: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// RedirectURL handles GET requests to redirect short URLs to their original destination
-func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	shortCode := vars["shortCode"]
-
-	url, err := h.urlService.GetURL(r.Context(), shortCode)
-	if err != nil {
-		if err == models.ErrURLNotFound {
-			http.Error(w, "URL not found", http.StatusNotFound)
-			return
-		} else if err == models.ErrURLExpired {
-			http.Error(w, "URL has expired", http.StatusGone)
-			return
-		} else if err == models.ErrInvalidShortCode {
-			http.Error(w, "Invalid short code", http.StatusBadRequest)
-			return
-		}
-		http.Error(w, "Failed to retrieve URL: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Record click asynchronously to not delay the redirect
-	go func() {
-		referer := r.Header.Get("Referer")
-		userAgent := r.Header.Get("User-Agent")
-		ipAddress := getUserIP(r)
-		// Using background context since request context will be canceled
-		h.urlService.RecordClick(r.Context(), url.ID, referer, userAgent, ipAddress)
-	}()
-
-	http.Redirect(w, r, url.OriginalURL, http.StatusFound)
-}
-
-// getUserIP extracts the client IP address from request
-func getUserIP(r *http.Request) string {
-	// Check for X-Forwarded-For header first (for proxied requests)
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		ips := strings.Split(ip, ",")
-		return strings.TrimSpace(ips[0])
-	}
-
-	// Fall back to RemoteAddr
-	ip = r.RemoteAddr
-	// Strip port if present
-	if i := strings.LastIndex(ip, ":"); i != -1 {
-		ip = ip[:i]
-	}
-	return ip
-}
\ No newline at end of file
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/middleware"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/render"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/ratelimit"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+)
+
+// errRateLimited is reported when a client exceeds the redirect rate limit
+var errRateLimited = errors.New("rate limited")
+
+// errInvalidWorkspaceID is reported for a workspace_id query parameter that isn't an ID
+var errInvalidWorkspaceID = errors.New("invalid workspace ID")
+
+// URLHandler handles HTTP requests for URL operations
+type URLHandler struct {
+	urlService       *service.URLService
+	workspaceService *service.WorkspaceService
+	renderer         *render.Renderer
+	limiter          *ratelimit.Limiter
+}
+
+// NewURLHandler creates a new URLHandler. A nil limiter disables redirect rate limiting.
+func NewURLHandler(urlService *service.URLService, workspaceService *service.WorkspaceService, renderer *render.Renderer, limiter *ratelimit.Limiter) *URLHandler {
+	return &URLHandler{
+		urlService:       urlService,
+		workspaceService: workspaceService,
+		renderer:         renderer,
+		limiter:          limiter,
+	}
+}
+
+// CreateURL handles POST requests to create a new short URL
+func (h *URLHandler) CreateURL(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateURLRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Get user IP; the link belongs to the signed-in user or their workspace
+	userIP := getUserIP(r)
+
+	// Create short URL
+	resp, err := h.urlService.CreateShortURL(r.Context(), req, userIP, actorFor(r))
+	if err != nil {
+		if accessError(w, err) || quotaError(w, err) {
+			return
+		} else if err == models.ErrDuplicateAlias {
+			http.Error(w, "Custom alias already exists", http.StatusConflict)
+			return
+		} else if err == models.ErrInvalidAlias {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == models.ErrAliasReserved || err == models.ErrAliasBlocked {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if errors.Is(err, models.ErrDestinationBlocked) || err == models.ErrDomainNotAllowed {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if err == models.ErrShortCodeExhausted {
+			http.Error(w, "Could not allocate a short code, please retry", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Failed to create short URL: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CheckAlias handles GET requests asking whether a custom alias is available
+func (h *URLHandler) CheckAlias(w http.ResponseWriter, r *http.Request) {
+	alias := mux.Vars(r)["alias"]
+	workspaceID, err := workspaceParam(r)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	availability, err := h.urlService.CheckAlias(r.Context(), r.URL.Query().Get("domain"), alias, actorFor(r), workspaceID)
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to check alias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(availability)
+}
+
+// GetURLByID handles GET requests to retrieve URL details by ID
+func (h *URLHandler) GetURLByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	details, err := h.urlService.GetURLByID(r.Context(), id, actorFor(r))
+	if err != nil {
+		if err == models.ErrURLNotFound {
+			http.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve URL: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag(details.URL))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// ReplaceURL handles PUT requests replacing a URL's destination, alias, expiry and preview
+// setting. Fields left out are cleared and unknown ones rejected, and If-Match makes the
+// replacement conditional.
+func (h *URLHandler) ReplaceURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReplaceURLRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if req.OriginalURL == "" {
+		http.Error(w, "original_url is required", http.StatusBadRequest)
+		return
+	}
+	edit, err := req.Edit(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.urlService.ReplaceURL(r.Context(), id, edit, ifMatch(r), actorFor(r))
+	if err != nil {
+		editError(w, "Failed to update URL", err)
+		return
+	}
+
+	writeURL(w, updated)
+}
+
+// mergePatchType is the media type of JSON merge patches
+const mergePatchType = "application/merge-patch+json"
+
+// maxPatchBytes caps the size of a PATCH body
+const maxPatchBytes = 64 << 10
+
+// PatchURL handles PATCH requests changing some of a URL's fields with a JSON merge patch.
+// Fields set to null are cleared, and If-Match makes the change conditional.
+func (h *URLHandler) PatchURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != mergePatchType {
+		w.Header().Set("Accept-Patch", mergePatchType)
+		http.Error(w, "PATCH takes a JSON merge patch ("+mergePatchType+")", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBytes))
+	if err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	updated, err := h.urlService.PatchURL(r.Context(), id, patch, ifMatch(r), actorFor(r))
+	if err != nil {
+		editError(w, "Failed to update URL", err)
+		return
+	}
+
+	writeURL(w, updated)
+}
+
+// DeleteURL handles DELETE requests to remove a URL
+func (h *URLHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.urlService.DeleteURL(r.Context(), id, actorFor(r))
+	if err != nil {
+		if accessError(w, err) {
+			return
+		} else if err == models.ErrURLNotFound {
+			http.Error(w, "URL not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete URL: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListOwnURLs handles GET requests listing the caller's links: those of the workspace named
+// by workspace_id, or otherwise the signed-in user's own. Anonymous callers must sign in.
+func (h *URLHandler) ListOwnURLs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ownURLsFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	resp, err := h.urlService.ListURLs(r.Context(), filter, actorFor(r))
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to list URLs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListURLs handles GET requests to list URLs, filtered by status, health and creator address;
+// deleted=true lists the trash
+func (h *URLHandler) ListURLs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.URLFilter{
+		Status: models.URLStatus(query.Get("status")),
+		Health: query.Get("health"),
+		UserIP: query.Get("user_ip"),
+	}
+	filter.Deleted, _ = strconv.ParseBool(query.Get("deleted"))
+	if filter.Health != "" && filter.Health != models.HealthBroken && filter.Health != models.HealthHealthy {
+		http.Error(w, "Invalid health filter: must be broken or healthy", http.StatusBadRequest)
+		return
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+
+	resp, err := h.urlService.ListURLs(r.Context(), filter, adminActor(r))
+	if err != nil {
+		if err == models.ErrInvalidStatus {
+			http.Error(w, "Invalid status: must be active, disabled, flagged or pending_review", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to list URLs: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateURLStatus handles PUT requests to change the moderation status of a URL
+func (h *URLHandler) UpdateURLStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateStatusRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	err = h.urlService.SetURLStatus(r.Context(), id, req.Status, req.Reason, adminActor(r))
+	if err != nil {
+		if err == models.ErrURLNotFound {
+			http.Error(w, "URL not found", http.StatusNotFound)
+			return
+		} else if err == models.ErrInvalidStatus {
+			http.Error(w, "Invalid status: must be active, disabled, flagged or pending_review", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update URL status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RedirectURL handles GET requests to redirect short URLs to their original destination
+func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	if h.limiter != nil && !h.limiter.Allow(getUserIP(r)) {
+		w.Header().Set("Retry-After", "60")
+		h.redirectError(w, r, errRateLimited)
+		return
+	}
+
+	url, err := h.urlService.GetURL(r.Context(), r.Host, shortCode)
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+
+	// Links that always show the interstitial redirect only once the visitor continues
+	if url.AlwaysPreview && r.URL.Query().Get("continue") == "" {
+		h.renderPreview(w, r, h.urlService.PreviewFor(r.Context(), url))
+		return
+	}
+
+	// Clicks count against the link owner's monthly quota
+	if err := h.urlService.AllowClick(r.Context(), url); err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+
+	// Record click asynchronously to not delay the redirect
+	go func() {
+		referer := r.Header.Get("Referer")
+		userAgent := r.Header.Get("User-Agent")
+		ipAddress := getUserIP(r)
+		// Using background context since request context will be canceled
+		h.urlService.RecordClick(r.Context(), url.ID, referer, userAgent, ipAddress)
+	}()
+
+	http.Redirect(w, r, url.OriginalURL, http.StatusFound)
+}
+
+// PreviewURL handles GET requests for a short code followed by "+", showing the destination without redirecting
+func (h *URLHandler) PreviewURL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	shortCode := vars["shortCode"]
+
+	preview, err := h.urlService.GetURLPreview(r.Context(), r.Host, shortCode)
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+
+	h.renderPreview(w, r, preview)
+}
+
+// renderPreview writes the interstitial page for browsers and the preview as JSON for API clients
+func (h *URLHandler) renderPreview(w http.ResponseWriter, r *http.Request, preview *models.URLPreview) {
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Cache-Control", "no-store")
+	if !render.WantsHTML(r) {
+		render.JSON(w, http.StatusOK, preview)
+		return
+	}
+
+	// Links that always show the interstitial need to be told the visitor has seen it
+	data := struct {
+		Preview     *models.URLPreview
+		ContinueURL string
+	}{
+		Preview:     preview,
+		ContinueURL: "/" + preview.ShortCode,
+	}
+	if preview.AlwaysPreview {
+		data.ContinueURL += "?continue=1"
+	}
+	h.renderer.HTML(w, http.StatusOK, "preview.html", data)
+}
+
+// redirectError renders a failed redirect as a branded page for browsers and JSON for API clients
+func (h *URLHandler) redirectError(w http.ResponseWriter, r *http.Request, err error) {
+	var page render.ErrorPage
+	switch err {
+	case models.ErrURLNotFound:
+		page = render.ErrorPage{
+			StatusCode: http.StatusNotFound,
+			Heading:    "Link not found",
+			Message:    "We couldn't find a link at this address. Check it for typos, or ask whoever shared it for a new one.",
+		}
+	case models.ErrURLExpired:
+		page = render.ErrorPage{
+			StatusCode: http.StatusGone,
+			Heading:    "Link expired",
+			Message:    "This link has expired and no longer points anywhere.",
+		}
+	case models.ErrInvalidShortCode:
+		page = render.ErrorPage{
+			StatusCode: http.StatusBadRequest,
+			Heading:    "Invalid link",
+			Message:    "This doesn't look like a valid short link.",
+		}
+	case models.ErrURLDisabled:
+		page = render.ErrorPage{
+			StatusCode: http.StatusForbidden,
+			Heading:    "Link disabled",
+			Message:    "This link has been disabled and is no longer available.",
+		}
+	case models.ErrClickQuotaExceeded:
+		setQuotaRetryAfter(w)
+		page = render.ErrorPage{
+			StatusCode: http.StatusTooManyRequests,
+			Heading:    "Link unavailable",
+			Message:    "This link has reached its limit of clicks for the month. Please try again later.",
+		}
+	case errRateLimited:
+		page = render.ErrorPage{
+			StatusCode: http.StatusTooManyRequests,
+			Heading:    "Slow down",
+			Message:    "You've opened too many links in a short time. Please wait a moment and try again.",
+		}
+	default:
+		log.Printf("Failed to retrieve URL: %v", err)
+		page = render.ErrorPage{
+			StatusCode: http.StatusInternalServerError,
+			Heading:    "Something went wrong",
+			Message:    "We couldn't open this link right now. Please try again later.",
+		}
+	}
+
+	h.renderer.Error(w, r, page)
+}
+
+// writeURL responds with a URL that was just saved and its ETag
+func writeURL(w http.ResponseWriter, u *models.URL) {
+	w.Header().Set("ETag", etag(u))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// etag is a URL's entity tag: its version, quoted
+func etag(u *models.URL) string {
+	return `"` + strconv.Itoa(u.Version) + `"`
+}
+
+// ifMatch returns the URL version an If-Match header asks for: 0 without one or for "*",
+// and -1, which no URL has, for anything but a single strong ETag of ours
+func ifMatch(r *http.Request) int {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return -1
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 1 {
+		return -1
+	}
+	return version
+}
+
+// editError writes the response for an error from editing a URL or one of its versions
+func editError(w http.ResponseWriter, prefix string, err error) {
+	if quotaError(w, err) {
+		return
+	}
+	switch {
+	case err == models.ErrVersionMismatch:
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case err == models.ErrVersionNotFound:
+		http.Error(w, "Version not found", http.StatusNotFound)
+	case err == models.ErrInvalidPatch, errors.Is(err, models.ErrInvalidURL):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrDestinationBlocked):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		aliasError(w, prefix, err)
+	}
+}
+
+// ownURLsFilter matches the links of the workspace named by the workspace_id query
+// parameter, or otherwise those that belong to the signed-in caller. Anonymous callers
+// get an empty filter, which ListURLs refuses: a network address doesn't prove whose
+// links they are.
+func ownURLsFilter(r *http.Request) (models.URLFilter, error) {
+	if id, err := workspaceParam(r); err != nil {
+		return models.URLFilter{}, err
+	} else if id != nil {
+		return models.URLFilter{WorkspaceID: *id}, nil
+	}
+	if user := middleware.CurrentUser(r); user != nil {
+		return models.URLFilter{UserID: user.ID}, nil
+	}
+	return models.URLFilter{}, nil
+}
+
+// workspaceParam parses the optional workspace_id query parameter
+func workspaceParam(r *http.Request) (*int64, error) {
+	raw := r.URL.Query().Get("workspace_id")
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, errInvalidWorkspaceID
+	}
+	return &id, nil
+}
+
+// actorFor returns who the request acts for. Signed-in users with the admin role act as admins.
+func actorFor(r *http.Request) models.Actor {
+	user := middleware.CurrentUser(r)
+	return models.Actor{
+		User:      user,
+		Admin:     user != nil && user.Role == models.RoleAdmin,
+		IP:        getUserIP(r),
+		RequestID: middleware.GetRequestID(r),
+	}
+}
+
+// adminActor returns the actor for a request on an admin route, which AdminAuth has already let through
+func adminActor(r *http.Request) models.Actor {
+	actor := actorFor(r)
+	actor.Admin = true
+	return actor
+}
+
+// getUserIP returns the client IP address, as worked out by the ClientIP middleware
+func getUserIP(r *http.Request) string {
+	return middleware.GetClientIP(r)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+)
+
+// UsageHandler handles usage and quota requests
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// Usage handles GET requests for the caller's personal usage this month
+func (h *UsageHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	h.writeUsage(w, r, 0)
+}
+
+// WorkspaceUsage handles GET requests for a workspace's usage this month
+func (h *UsageHandler) WorkspaceUsage(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	h.writeUsage(w, r, workspaceID)
+}
+
+// writeUsage writes the usage of a workspace, or of the caller's personal links for ID zero
+func (h *UsageHandler) writeUsage(w http.ResponseWriter, r *http.Request, workspaceID int64) {
+	usage, err := h.usageService.Usage(r.Context(), actorFor(r), workspaceID)
+	if err != nil {
+		if accessError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to get usage: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// SetWorkspaceQuota handles PUT requests giving a workspace its own limits. Omitted or
+// null limits fall back to the configured defaults.
+func (h *UsageHandler) SetWorkspaceQuota(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.QuotaOverrides
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.usageService.SetWorkspaceQuota(r.Context(), workspaceID, &req); err != nil {
+		if err == models.ErrInvalidQuota {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err == models.ErrWorkspaceNotFound {
+			http.Error(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to set quota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// quotaError writes the response for an exceeded quota and reports whether err was one.
+// Monthly quotas answer 429 with a Retry-After for the start of next month; the active link
+// quota answers 402, as only deleting links or a higher limit frees it up.
+func quotaError(w http.ResponseWriter, err error) bool {
+	switch err {
+	case models.ErrLinkQuotaExceeded, models.ErrClickQuotaExceeded:
+		setQuotaRetryAfter(w)
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case models.ErrActiveLinkQuotaExceeded:
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		return false
+	}
+	return true
+}
+
+// setQuotaRetryAfter tells the client to come back when the monthly counters reset
+func setQuotaRetryAfter(w http.ResponseWriter) {
+	retryAfter := int(time.Until(service.NextQuotaPeriod(time.Time{})).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ListVersions handles GET requests for a URL's version history
+func (h *URLHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.urlService.ListVersions(r.Context(), id, actorFor(r))
+	if err != nil {
+		editError(w, "Failed to list versions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// RestoreVersion handles POST requests rolling a URL back to an earlier version. If-Match
+// makes the rollback conditional on the URL's current version.
+func (h *URLHandler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid URL ID", http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.urlService.RestoreVersion(r.Context(), id, version, ifMatch(r), actorFor(r))
+	if err != nil {
+		editError(w, "Failed to restore version", err)
+		return
+	}
+
+	writeURL(w, restored)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+)
+
+// WorkspaceHandler handles workspace, membership and invitation requests
+type WorkspaceHandler struct {
+	workspaceService *service.WorkspaceService
+}
+
+// NewWorkspaceHandler creates a new WorkspaceHandler
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+// ListWorkspaces handles GET requests for the caller's workspaces
+func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.workspaceService.ListWorkspaces(r.Context(), actorFor(r))
+	if err != nil {
+		workspaceError(w, "Failed to list workspaces", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workspaces)
+}
+
+// CreateWorkspace handles POST requests creating a workspace owned by the caller
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	workspace, err := h.workspaceService.CreateWorkspace(r.Context(), actorFor(r), req)
+	if err != nil {
+		workspaceError(w, "Failed to create workspace", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(workspace)
+}
+
+// ListMembers handles GET requests for a workspace's members
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(r.Context(), actorFor(r), workspaceID)
+	if err != nil {
+		workspaceError(w, "Failed to list members", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// UpdateMember handles PUT requests changing a member's role
+func (h *WorkspaceHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.ParseInt(vars["userID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.workspaceService.SetMemberRole(r.Context(), actorFor(r), workspaceID, userID, req.Role); err != nil {
+		workspaceError(w, "Failed to update member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember handles DELETE requests taking a member out of a workspace, or leaving it
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.ParseInt(vars["userID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(r.Context(), actorFor(r), workspaceID, userID); err != nil {
+		workspaceError(w, "Failed to remove member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListInvitations handles GET requests for a workspace's pending invitations
+func (h *WorkspaceHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	invitations, err := h.workspaceService.ListInvitations(r.Context(), actorFor(r), workspaceID)
+	if err != nil {
+		workspaceError(w, "Failed to list invitations", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// Invite handles POST requests inviting someone to a workspace. The response carries the
+// token and accept URL to pass on to the invitee; they are not shown again.
+func (h *WorkspaceHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	workspaceID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	invitation, err := h.workspaceService.Invite(r.Context(), actorFor(r), workspaceID, req)
+	if err != nil {
+		workspaceError(w, "Failed to create invitation", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// RevokeInvitation handles DELETE requests for a pending invitation
+func (h *WorkspaceHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	workspaceID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return
+	}
+	invitationID, err := strconv.ParseInt(vars["invitationID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.workspaceService.RevokeInvitation(r.Context(), actorFor(r), workspaceID, invitationID); err != nil {
+		workspaceError(w, "Failed to revoke invitation", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles POST requests joining the workspace an invitation is for
+func (h *WorkspaceHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req models.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	workspaceID, err := h.workspaceService.AcceptInvitation(r.Context(), actorFor(r), req.Token)
+	if err != nil {
+		workspaceError(w, "Failed to accept invitation", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"workspace_id": workspaceID})
+}
+
+// accessError writes the response for a failed workspace permission check and reports
+// whether err was one
+func accessError(w http.ResponseWriter, err error) bool {
+	switch err {
+	case models.ErrSignInRequired:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case models.ErrForbidden:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case models.ErrWorkspaceNotFound:
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	default:
+		return false
+	}
+	return true
+}
+
+// workspaceError writes the response for a failed workspace operation
+func workspaceError(w http.ResponseWriter, prefix string, err error) {
+	if accessError(w, err) {
+		return
+	}
+	switch err {
+	case models.ErrMemberNotFound:
+		http.Error(w, "Member not found", http.StatusNotFound)
+	case models.ErrInvitationNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case models.ErrInvitationMismatch:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case models.ErrLastOwner, models.ErrAlreadyMember:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case models.ErrInvalidWorkspaceName, models.ErrInvalidWorkspaceRole, models.ErrInvalidEmail:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, prefix+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// AdminAuth is a middleware that only lets requests carrying the admin bearer token, or made
+// by a signed-in admin, through. An empty token leaves the protected routes to admins only.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := CurrentUser(r); user != nil && user.Role == models.RoleAdmin {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if token == "" {
+				http.Error(w, "Admin API is disabled", http.StatusForbidden)
+				return
+			}
+
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
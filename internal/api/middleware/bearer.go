@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/sso"
+)
+
+// BearerAuthenticator resolves a single sign-on JWT to the user it was issued to
+type BearerAuthenticator interface {
+	AuthenticateBearer(ctx context.Context, raw string) (*models.User, error)
+}
+
+// BearerTokens is a middleware that signs in requests carrying an identity provider's JWT
+// as a bearer token, so CurrentUser returns its user. Opaque bearer tokens such as the admin
+// token are passed through untouched; JWTs that fail verification are rejected.
+func BearerTokens(auth BearerAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !sso.LooksLikeJWT(token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := auth.AuthenticateBearer(r.Context(), token)
+			if err != nil {
+				log.Printf("Rejected bearer token: %v", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			state := &sessionState{user: user, secure: SecureCookies(r)}
+			state.once.Do(func() {})
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, state)))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/sso"
+)
+
+// fakeBearerAuthenticator accepts the one token it knows; verifying real JWTs is tested in package sso
+type fakeBearerAuthenticator struct {
+	token string
+	user  *models.User
+	calls int
+}
+
+func (a *fakeBearerAuthenticator) AuthenticateBearer(ctx context.Context, raw string) (*models.User, error) {
+	a.calls++
+	if raw != a.token {
+		return nil, sso.ErrInvalidToken
+	}
+	return a.user, nil
+}
+
+// serveBearer runs a request with the Authorization header through BearerTokens, returning the
+// response and the user the handler saw, if it was reached
+func serveBearer(auth BearerAuthenticator, authorization string) (*httptest.ResponseRecorder, *models.User, bool) {
+	var user *models.User
+	var reached bool
+	handler := BearerTokens(auth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		user = CurrentUser(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/urls", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, user, reached
+}
+
+func TestBearerTokensSignsInValidTokens(t *testing.T) {
+	auth := &fakeBearerAuthenticator{
+		token: "header.payload.signature",
+		user:  &models.User{ID: 7, Email: "someone@example.com", Role: models.RoleUser},
+	}
+
+	rec, user, reached := serveBearer(auth, "Bearer header.payload.signature")
+
+	if !reached || rec.Code != http.StatusOK {
+		t.Fatalf("reached = %v, status = %d; want the request served", reached, rec.Code)
+	}
+	if user != auth.user {
+		t.Errorf("CurrentUser = %+v, want %+v", user, auth.user)
+	}
+}
+
+func TestBearerTokensRejectsInvalidTokens(t *testing.T) {
+	auth := &fakeBearerAuthenticator{token: "header.payload.signature", user: &models.User{ID: 7}}
+
+	rec, _, reached := serveBearer(auth, "Bearer header.payload.forged")
+
+	if reached {
+		t.Fatal("handler was reached with an invalid token")
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer error="invalid_token"` {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+}
+
+func TestBearerTokensPassesOtherRequestsThrough(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"no authorization", ""},
+		{"opaque token", "Bearer an-opaque-admin-token"},
+		{"basic auth", "Basic dXNlcjpwYXNz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &fakeBearerAuthenticator{}
+
+			rec, user, reached := serveBearer(auth, tt.authorization)
+
+			if !reached || rec.Code != http.StatusOK {
+				t.Fatalf("reached = %v, status = %d; want the request passed through", reached, rec.Code)
+			}
+			if user != nil {
+				t.Errorf("CurrentUser = %+v, want nil", user)
+			}
+			if auth.calls != 0 {
+				t.Errorf("authenticator called %d times, want 0", auth.calls)
+			}
+		})
+	}
+}
@@ -1,26 +1,27 @@
-package middleware
-
-import (
-	"log"
-	"net/http"
-	"time"
-)
-
-// Logging is a middleware that logs requests
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		
-		// Call the next handler
-		next.ServeHTTP(w, r)
-		
-		// Log the request details
-		log.Printf(
-			"%s %s %s %s",
-			r.Method,
-			r.RequestURI,
-			r.RemoteAddr,
-			time.Since(start),
-		)
-	})
-}
\ No newline at end of file
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Logging is a middleware that logs requests
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Call the next handler
+		next.ServeHTTP(w, r)
+
+		// Log the request details
+		log.Printf(
+			"%s %s %s %s %s",
+			r.Method,
+			r.RequestURI,
+			GetClientIP(r),
+			time.Since(start),
+			GetRequestID(r),
+		)
+	})
+}
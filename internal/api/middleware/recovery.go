@@ -1,20 +1,20 @@
-package middleware
-
-import (
-	"log"
-	"net/http"
-)
-
-// Recovery is a middleware that recovers from panics
-func Recovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-			}
-		}()
-		
-		next.ServeHTTP(w, r)
-	})
+package middleware
+
+import (
+	"log"
+	"net/http"
+)
+
+// Recovery is a middleware that recovers from panics
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("Panic recovered: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+		
+		next.ServeHTTP(w, r)
+	})
 }
\ No newline at end of file
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// SessionCookie holds the token of a signed-in browser's session
+const SessionCookie = "ziply_session"
+
+// SessionAuthenticator resolves a session cookie's token to its session and user
+type SessionAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*models.Session, *models.User, error)
+}
+
+type sessionKey struct{}
+
+// sessionState is the session of one request. It is looked up on first use, so requests
+// that never ask for the user, such as redirects, don't touch the database.
+type sessionState struct {
+	auth   SessionAuthenticator
+	token  string
+	secure bool
+
+	once    sync.Once
+	session *models.Session
+	user    *models.User
+}
+
+func (s *sessionState) load(ctx context.Context) {
+	s.once.Do(func() {
+		if s.token == "" {
+			return
+		}
+		session, user, err := s.auth.Authenticate(ctx, s.token)
+		if err != nil {
+			if err != models.ErrSessionNotFound && err != models.ErrUserNotFound {
+				log.Printf("Failed to load session: %v", err)
+			}
+			return
+		}
+		s.session, s.user = session, user
+	})
+}
+
+// Sessions is a middleware that makes the signed-in user available to handlers through
+// CurrentUser. secure marks the cookies it sets as HTTPS-only.
+func Sessions(auth SessionAuthenticator, secure bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &sessionState{auth: auth, secure: secure}
+			if cookie, err := r.Cookie(SessionCookie); err == nil {
+				state.token = cookie.Value
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, state)))
+		})
+	}
+}
+
+// sessionFrom returns the request's session state, or nil outside the Sessions middleware
+func sessionFrom(r *http.Request) *sessionState {
+	state, _ := r.Context().Value(sessionKey{}).(*sessionState)
+	if state != nil {
+		state.load(r.Context())
+	}
+	return state
+}
+
+// CurrentUser returns the signed-in user, or nil for anonymous requests
+func CurrentUser(r *http.Request) *models.User {
+	if state := sessionFrom(r); state != nil {
+		return state.user
+	}
+	return nil
+}
+
+// CurrentSession returns the signed-in user's session, or nil for anonymous requests
+func CurrentSession(r *http.Request) *models.Session {
+	if state := sessionFrom(r); state != nil {
+		return state.session
+	}
+	return nil
+}
+
+// SessionToken returns the raw session token the request carried, if any
+func SessionToken(r *http.Request) string {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// SetSessionCookie stores a new session's token in the browser
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   SecureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie removes the session cookie from the browser
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   SecureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SecureCookies reports whether cookies set on this request should be HTTPS-only
+func SecureCookies(r *http.Request) bool {
+	state, _ := r.Context().Value(sessionKey{}).(*sessionState)
+	return state != nil && state.secure
+}
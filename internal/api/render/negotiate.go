@@ -0,0 +1,44 @@
+package render
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WantsHTML reports whether the client prefers an HTML response over JSON,
+// based on the quality values in its Accept header. Missing Accept means JSON.
+func WantsHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch mediaType {
+		case "text/html", "application/xhtml+xml", "text/*":
+			htmlQ = max(htmlQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "*/*":
+			// Wildcards alone don't express a preference for either format
+			jsonQ = max(jsonQ, q*0.5)
+		}
+	}
+
+	return htmlQ > 0 && htmlQ >= jsonQ
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Renderer holds page templates parsed once at startup
+type Renderer struct {
+	pages       map[string]*template.Template
+	base        fs.FS
+	overrideDir string
+	funcs       template.FuncMap
+	reload      bool
+}
+
+// New parses every *.html template in base. A template with the same name in
+// overrideDir replaces the built-in one, so deployments can rebrand pages. funcs
+// are available to every template. With reload set, templates are parsed again
+// on every render so edits show up without a restart during development.
+func New(base fs.FS, overrideDir string, funcs template.FuncMap, reload bool) (*Renderer, error) {
+	r := &Renderer{
+		base:        base,
+		overrideDir: overrideDir,
+		funcs:       funcs,
+		reload:      reload,
+	}
+
+	pages, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	return r, nil
+}
+
+// parse reads and parses every template
+func (r *Renderer) parse() (map[string]*template.Template, error) {
+	names, err := fs.Glob(r.base, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, name := range names {
+		src, err := fs.ReadFile(r.base, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+
+		if r.overrideDir != "" {
+			override, err := os.ReadFile(filepath.Join(r.overrideDir, name))
+			if err == nil {
+				src = override
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read template override %s: %w", name, err)
+			}
+		}
+
+		tmpl, err := template.New(path.Base(name)).Funcs(r.funcs).Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		pages[name] = tmpl
+	}
+
+	return pages, nil
+}
+
+// HTML renders the named page with the given status code
+func (r *Renderer) HTML(w http.ResponseWriter, status int, name string, data interface{}) {
+	pages := r.pages
+	if r.reload {
+		var err error
+		if pages, err = r.parse(); err != nil {
+			log.Printf("Failed to reload templates: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tmpl, ok := pages[name]
+	if !ok {
+		log.Printf("Template %s not found", name)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	// Render into a buffer first so a template error doesn't leave a half-written page
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("Failed to render template %s: %v", name, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// JSON writes v as a JSON response with the given status code
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ErrorPage describes an error outcome shown to the visitor
+type ErrorPage struct {
+	Title      string
+	Heading    string
+	Message    string
+	StatusCode int
+}
+
+// Error writes an error as the branded HTML page for browsers and as JSON for API clients
+func (r *Renderer) Error(w http.ResponseWriter, req *http.Request, page ErrorPage) {
+	if page.Title == "" {
+		page.Title = page.Heading
+	}
+
+	w.Header().Add("Vary", "Accept")
+	if WantsHTML(req) {
+		r.HTML(w, page.StatusCode, "error.html", page)
+		return
+	}
+
+	JSON(w, page.StatusCode, struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+	}{
+		Error:   page.Heading,
+		Message: page.Message,
+		Status:  page.StatusCode,
+	})
+}
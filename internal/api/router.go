@@ -1,49 +1,124 @@
-package api
-
-import (
-	"net/http"
-
-	"github.com/gorilla/mux"
-	"github.com/rakheshkrishna2005/url-shortener/internal/api/handlers"
-	"github.com/rakheshkrishna2005/url-shortener/internal/api/middleware"
-)
-
-// NewRouter sets up and configures the API router
-func NewRouter(urlHandler *handlers.URLHandler, healthHandler *handlers.HealthHandler) *mux.Router {
-	router := mux.NewRouter()
-
-	// Apply common middleware
-	router.Use(middleware.Logging)
-	router.Use(middleware.Recovery)
-
-	// API routes
-	api := router.PathPrefix("/api/v1").Subrouter()
-	
-	// URL endpoints
-	urlsRouter := api.PathPrefix("/urls").Subrouter()
-	urlsRouter.HandleFunc("", urlHandler.CreateURL).Methods(http.MethodPost)
-	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.GetURLByID).Methods(http.MethodGet)
-	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.UpdateURL).Methods(http.MethodPut)
-	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.DeleteURL).Methods(http.MethodDelete)
-
-	// Health check
-	router.HandleFunc("/health", healthHandler.HealthCheck).Methods(http.MethodGet)
-
-	// Redirect handler
-	router.HandleFunc("/{shortCode:[a-zA-Z0-9]+}", urlHandler.RedirectURL).Methods(http.MethodGet)
-
-	// Serve static files and home page
-	fs := http.FileServer(http.Dir("./web/static"))
-	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
-	
-	// Home page handler - we'll add this later
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
-		}
-		http.ServeFile(w, r, "./web/templates/index.html")
-	}).Methods(http.MethodGet)
-
-	return router
-}
\ No newline at end of file
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/handlers"
+	"github.com/rakheshkrishna2005/url-shortener/internal/api/middleware"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+// ReservedPaths are the first path segments the router serves itself, so they can't be used as aliases
+var ReservedPaths = []string{"api", "static", "dashboard", "login", "logout", "register", "health", "livez", "readyz", "metrics"}
+
+// NewRouter sets up and configures the API router. A nil metrics handler leaves /metrics unregistered.
+// clientIP is the middleware that works out the client's address, sessions the one that loads the
+// signed-in user from the session cookie, and bearer the one that accepts single sign-on JWTs on
+// API routes; a nil bearer middleware leaves them off.
+func NewRouter(urlHandler *handlers.URLHandler, domainHandler *handlers.DomainHandler, workspaceHandler *handlers.WorkspaceHandler, usageHandler *handlers.UsageHandler, auditHandler *handlers.AuditHandler, authHandler *handlers.AuthHandler, clientIP, sessions, bearer func(http.Handler) http.Handler, healthHandler *handlers.HealthHandler, metricsHandler http.Handler, static http.Handler, adminToken string) *mux.Router {
+	router := mux.NewRouter()
+
+	// Apply common middleware
+	router.Use(middleware.RequestID)
+	router.Use(clientIP)
+	router.Use(middleware.Logging)
+	router.Use(middleware.Recovery)
+	router.Use(sessions)
+	router.Use(middleware.CSRF)
+
+	// API routes
+	api := router.PathPrefix("/api/v1").Subrouter()
+	if bearer != nil {
+		api.Use(bearer)
+	}
+
+	// URL endpoints
+	urlsRouter := api.PathPrefix("/urls").Subrouter()
+	urlsRouter.HandleFunc("", urlHandler.CreateURL).Methods(http.MethodPost)
+	urlsRouter.HandleFunc("", urlHandler.ListOwnURLs).Methods(http.MethodGet)
+	urlsRouter.HandleFunc("/trash", urlHandler.ListTrash).Methods(http.MethodGet)
+	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.GetURLByID).Methods(http.MethodGet)
+	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.ReplaceURL).Methods(http.MethodPut)
+	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.PatchURL).Methods(http.MethodPatch)
+	urlsRouter.HandleFunc("/{id:[0-9]+}", urlHandler.DeleteURL).Methods(http.MethodDelete)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/restore", urlHandler.RestoreURL).Methods(http.MethodPost)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/aliases", urlHandler.ListAliases).Methods(http.MethodGet)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/aliases", urlHandler.AddAlias).Methods(http.MethodPost)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/aliases/{alias:"+utils.AliasPattern+"}", urlHandler.RenameAlias).Methods(http.MethodPut)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/aliases/{alias:"+utils.AliasPattern+"}", urlHandler.RemoveAlias).Methods(http.MethodDelete)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/versions", urlHandler.ListVersions).Methods(http.MethodGet)
+	urlsRouter.HandleFunc("/{id:[0-9]+}/versions/{version:[0-9]+}/restore", urlHandler.RestoreVersion).Methods(http.MethodPost)
+
+	// Alias availability
+	api.HandleFunc("/aliases/{alias}", urlHandler.CheckAlias).Methods(http.MethodGet)
+
+	// Domains new links can be created on
+	api.HandleFunc("/domains", domainHandler.AvailableDomains).Methods(http.MethodGet)
+	api.HandleFunc("/domains", domainHandler.RegisterDomain).Methods(http.MethodPost)
+	api.HandleFunc("/domains/{id:[0-9]+}/verify", domainHandler.VerifyDomain).Methods(http.MethodPost)
+
+	// Workspaces, their members and invitations
+	workspacesRouter := api.PathPrefix("/workspaces").Subrouter()
+	workspacesRouter.HandleFunc("", workspaceHandler.ListWorkspaces).Methods(http.MethodGet)
+	workspacesRouter.HandleFunc("", workspaceHandler.CreateWorkspace).Methods(http.MethodPost)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/members", workspaceHandler.ListMembers).Methods(http.MethodGet)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/members/{userID:[0-9]+}", workspaceHandler.UpdateMember).Methods(http.MethodPut)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/members/{userID:[0-9]+}", workspaceHandler.RemoveMember).Methods(http.MethodDelete)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/invitations", workspaceHandler.ListInvitations).Methods(http.MethodGet)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/invitations", workspaceHandler.Invite).Methods(http.MethodPost)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/invitations/{invitationID:[0-9]+}", workspaceHandler.RevokeInvitation).Methods(http.MethodDelete)
+	workspacesRouter.HandleFunc("/{id:[0-9]+}/usage", usageHandler.WorkspaceUsage).Methods(http.MethodGet)
+	api.HandleFunc("/invitations/accept", workspaceHandler.AcceptInvitation).Methods(http.MethodPost)
+
+	// Usage against quotas
+	api.HandleFunc("/usage", usageHandler.Usage).Methods(http.MethodGet)
+
+	// Admin endpoints
+	adminRouter := api.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.AdminAuth(adminToken))
+	adminRouter.HandleFunc("/urls", urlHandler.ListURLs).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/urls/{id:[0-9]+}/status", urlHandler.UpdateURLStatus).Methods(http.MethodPut)
+	adminRouter.HandleFunc("/domains", domainHandler.ListDomains).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/domains", domainHandler.CreateDomain).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/domains/{id:[0-9]+}", domainHandler.UpdateDomain).Methods(http.MethodPut)
+	adminRouter.HandleFunc("/workspaces/{id:[0-9]+}/quota", usageHandler.SetWorkspaceQuota).Methods(http.MethodPut)
+	adminRouter.HandleFunc("/audit", auditHandler.ListAuditLog).Methods(http.MethodGet)
+
+	// Health checks
+	router.HandleFunc("/health", healthHandler.HealthCheck).Methods(http.MethodGet)
+	router.HandleFunc("/livez", healthHandler.Livez).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", healthHandler.Readyz).Methods(http.MethodGet)
+
+	// Prometheus metrics
+	if metricsHandler != nil {
+		router.Handle("/metrics", metricsHandler).Methods(http.MethodGet)
+	}
+
+	// Web UI accounts
+	router.HandleFunc("/login", authHandler.LoginPage).Methods(http.MethodGet)
+	router.HandleFunc("/login", authHandler.Login).Methods(http.MethodPost)
+	router.HandleFunc("/login/sso", authHandler.SSOLogin).Methods(http.MethodGet)
+	router.HandleFunc("/login/sso/callback", authHandler.SSOCallback).Methods(http.MethodGet)
+	router.HandleFunc("/register", authHandler.RegisterPage).Methods(http.MethodGet)
+	router.HandleFunc("/register", authHandler.Register).Methods(http.MethodPost)
+	router.HandleFunc("/logout", authHandler.Logout).Methods(http.MethodPost)
+
+	// Link management dashboard; registered before the code routes, which would match it too
+	router.HandleFunc("/dashboard", urlHandler.Dashboard).Methods(http.MethodGet)
+
+	// Preview page, e.g. /abc123+
+	// Links answer on their generated code and on any alias. Aliases may use any letter or
+	// digit, so the route can't be narrowed to the code alphabet; the service checks codes.
+	router.HandleFunc("/{shortCode:"+utils.AliasPattern+"}+", urlHandler.PreviewURL).Methods(http.MethodGet)
+
+	// Redirect handler, resolved against the domain in the Host header
+	router.HandleFunc("/{shortCode:"+utils.AliasPattern+"}", urlHandler.RedirectURL).Methods(http.MethodGet)
+
+	// Serve static files and home page
+	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", static))
+
+	router.HandleFunc("/", urlHandler.Home).Methods(http.MethodGet)
+
+	return router
+}
@@ -0,0 +1,210 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// hashLength is the number of hex characters of the content hash put into fingerprinted names
+const hashLength = 12
+
+// asset is a static file held in memory with its precompressed variants
+type asset struct {
+	contentType string
+	hash        string
+	data        []byte
+	gzip        []byte
+	brotli      []byte
+}
+
+// Assets serves static files. In production every file is fingerprinted with its content
+// hash so it can be cached forever; in development files are read from disk on each request.
+type Assets struct {
+	prefix        string
+	files         map[string]*asset
+	fingerprinted map[string]*asset
+	dev           http.Handler
+}
+
+// New loads every file in fsys, which is served under prefix (e.g. "/static/"), and
+// precompresses the ones that benefit from it.
+func New(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		prefix:        prefix,
+		files:         make(map[string]*asset),
+		fingerprinted: make(map[string]*asset),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		file := &asset{
+			contentType: mime.TypeByExtension(path.Ext(name)),
+			hash:        hex.EncodeToString(sum[:])[:hashLength],
+			data:        data,
+		}
+		if compressible(file.contentType) {
+			if file.gzip, err = gzipBytes(data); err != nil {
+				return fmt.Errorf("failed to gzip %s: %w", name, err)
+			}
+			if file.brotli, err = brotliBytes(data); err != nil {
+				return fmt.Errorf("failed to brotli %s: %w", name, err)
+			}
+		}
+
+		a.files[name] = file
+		a.fingerprinted[fingerprint(name, file.hash)] = file
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load static assets: %w", err)
+	}
+
+	return a, nil
+}
+
+// NewDev serves the files in fsys as they are on disk, without fingerprints or long-lived caching
+func NewDev(fsys fs.FS, prefix string) *Assets {
+	return &Assets{
+		prefix: prefix,
+		dev:    http.FileServer(http.FS(fsys)),
+	}
+}
+
+// Path returns the URL of a static file, fingerprinted when the file is known.
+// Templates call it as {{asset "css/style.css"}}.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if file, ok := a.files[name]; ok {
+		return a.prefix + fingerprint(name, file.hash)
+	}
+	return a.prefix + name
+}
+
+// ServeHTTP serves a static file; the request path must already have the prefix stripped
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.dev != nil {
+		w.Header().Set("Cache-Control", "no-cache")
+		a.dev.ServeHTTP(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	file, ok := a.fingerprinted[name]
+	if ok {
+		// The URL changes whenever the content does
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else if file, ok = a.files[name]; ok {
+		// Unfingerprinted URLs, e.g. from template overrides, must be revalidated
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, encoding := file.data, ""
+	if file.gzip != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		acceptEncoding := r.Header.Get("Accept-Encoding")
+		if acceptsEncoding(acceptEncoding, "br") {
+			data, encoding = file.brotli, "br"
+		} else if acceptsEncoding(acceptEncoding, "gzip") {
+			data, encoding = file.gzip, "gzip"
+		}
+	}
+
+	// Each encoding is a different representation and needs its own validator
+	etag := file.hash
+	if encoding != "" {
+		etag += "-" + encoding
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Set("ETag", strconv.Quote(etag))
+	if file.contentType != "" {
+		w.Header().Set("Content-Type", file.contentType)
+	}
+
+	// ServeContent answers If-None-Match with 304 using the ETag set above
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// fingerprint inserts hash before the file extension: css/style.css becomes css/style.<hash>.css
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// compressible reports whether a content type is text-like and worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "javascript") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding with a non-zero quality
+func acceptsEncoding(header, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipBytes compresses data with gzip at the best compression level
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// brotliBytes compresses data with brotli at the best compression level
+func brotliBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := bw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
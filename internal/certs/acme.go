@@ -0,0 +1,75 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Options configures ACME certificate issuance
+type Options struct {
+	Email string
+	// DirectoryURL is the ACME server's directory, Let's Encrypt by default.
+	// Point it at a local test server such as Pebble during development.
+	DirectoryURL string
+	// CAFile holds extra PEM roots to trust when talking to the ACME server, e.g. Pebble's
+	CAFile string
+}
+
+// NewManager creates an autocert manager that issues and renews certificates for the
+// hosts hostPolicy allows, storing account keys and certificates in cache.
+func NewManager(opts Options, cache autocert.Cache, hostPolicy autocert.HostPolicy) (*autocert.Manager, error) {
+	if opts.DirectoryURL == "" {
+		opts.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+
+	httpClient, err := newHTTPClient(opts.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: hostPolicy,
+		Email:      opts.Email,
+		Client: &acme.Client{
+			DirectoryURL: opts.DirectoryURL,
+			HTTPClient:   httpClient,
+		},
+	}, nil
+}
+
+// newHTTPClient returns the client used to reach the ACME server, trusting caFile in addition to the system roots
+func newHTTPClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return http.DefaultClient, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading ACME CA file: %w", err)
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ACME CA file %s", caFile)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}, nil
+}
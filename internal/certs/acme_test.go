@@ -0,0 +1,415 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/repository/postgres"
+	"github.com/rakheshkrishna2005/url-shortener/internal/service"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// testCA is a stub ACME server. It validates tls-alpn-01 challenges by asking the manager for
+// the challenge certificate, the way a CA's handshake would, and signs CSRs with its own root.
+type testCA struct {
+	*httptest.Server
+	t       *testing.T
+	root    *x509.Certificate
+	rootKey *ecdsa.PrivateKey
+	// manager answers challenges; set it before requesting certificates
+	manager *autocert.Manager
+
+	mu     sync.Mutex
+	orders []*testOrder
+}
+
+type testOrder struct {
+	host       string
+	authorized bool
+	certDER    []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test ACME Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+
+	ca := &testCA{t: t, root: root, rootKey: rootKey}
+	ca.Server = httptest.NewTLSServer(http.HandlerFunc(ca.serve))
+	t.Cleanup(ca.Close)
+	return ca
+}
+
+// caFile writes the stub server's TLS certificate to a file for Options.CAFile
+func (ca *testCA) caFile() string {
+	path := filepath.Join(ca.t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate().Raw})
+	if err := os.WriteFile(path, block, 0o600); err != nil {
+		ca.t.Fatalf("write CA file: %v", err)
+	}
+	return path
+}
+
+// issued returns the hosts certificates were issued for
+func (ca *testCA) issued() []string {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	var hosts []string
+	for _, o := range ca.orders {
+		if o.certDER != nil {
+			hosts = append(hosts, o.host)
+		}
+	}
+	return hosts
+}
+
+func (ca *testCA) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   ca.URL + "/nonce",
+			"newAccount": ca.URL + "/account",
+			"newOrder":   ca.URL + "/order",
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		// HEAD /nonce only needs the header
+		return
+	}
+
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		http.Error(w, "malformed JWS", http.StatusBadRequest)
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	resource, index := r.URL.Path, -1
+	if i := strings.LastIndex(resource, "/"); i > 0 {
+		fmt.Sscanf(resource[i+1:], "%d", &index)
+		resource = resource[:i]
+	}
+	if index >= len(ca.orders) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch resource {
+	case "/account":
+		w.Header().Set("Location", ca.URL+"/account/0")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": "valid"})
+	case "/order":
+		if index >= 0 {
+			ca.writeOrder(w, index, http.StatusOK)
+			return
+		}
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		json.Unmarshal(payload, &req)
+		if len(req.Identifiers) != 1 {
+			http.Error(w, "one identifier per order", http.StatusBadRequest)
+			return
+		}
+		ca.orders = append(ca.orders, &testOrder{host: req.Identifiers[0].Value})
+		ca.writeOrder(w, len(ca.orders)-1, http.StatusCreated)
+	case "/authz":
+		ca.writeJSON(w, ca.authorization(index))
+	case "/challenge":
+		if err := ca.validate(ca.orders[index].host); err != nil {
+			ca.t.Errorf("challenge for %s: %v", ca.orders[index].host, err)
+		} else {
+			ca.orders[index].authorized = true
+		}
+		ca.writeJSON(w, ca.challenge(index))
+	case "/finalize":
+		var req struct{ CSR string }
+		json.Unmarshal(payload, &req)
+		if err := ca.sign(index, req.CSR); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ca.writeOrder(w, index, http.StatusOK)
+	case "/cert":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.orders[index].certDER})
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.root.Raw})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (ca *testCA) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (ca *testCA) writeOrder(w http.ResponseWriter, index, status int) {
+	o := ca.orders[index]
+	order := map[string]interface{}{
+		"status":         "pending",
+		"identifiers":    []map[string]string{{"type": "dns", "value": o.host}},
+		"authorizations": []string{fmt.Sprintf("%s/authz/%d", ca.URL, index)},
+		"finalize":       fmt.Sprintf("%s/finalize/%d", ca.URL, index),
+	}
+	switch {
+	case o.certDER != nil:
+		order["status"] = "valid"
+		order["certificate"] = fmt.Sprintf("%s/cert/%d", ca.URL, index)
+	case o.authorized:
+		order["status"] = "ready"
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/order/%d", ca.URL, index))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (ca *testCA) authorization(index int) map[string]interface{} {
+	status := "pending"
+	if ca.orders[index].authorized {
+		status = "valid"
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": ca.orders[index].host},
+		"challenges": []interface{}{ca.challenge(index)},
+	}
+}
+
+func (ca *testCA) challenge(index int) map[string]string {
+	status := "pending"
+	if ca.orders[index].authorized {
+		status = "valid"
+	}
+	return map[string]string{
+		"type":   "tls-alpn-01",
+		"url":    fmt.Sprintf("%s/challenge/%d", ca.URL, index),
+		"token":  fmt.Sprintf("token-%d", index),
+		"status": status,
+	}
+}
+
+// validate asks the manager for the tls-alpn-01 certificate it would present for host
+func (ca *testCA) validate(host string) error {
+	cert, err := ca.manager.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      host,
+		SupportedProtos: []string{acme.ALPNProto},
+	})
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	return leaf.VerifyHostname(host)
+}
+
+// sign issues the order's certificate for a base64url DER CSR
+func (ca *testCA) sign(index int, encoded string) error {
+	o := ca.orders[index]
+	if !o.authorized {
+		return fmt.Errorf("order for %s is not authorized", o.host)
+	}
+	der, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(index) + 2),
+		Subject:      pkix.Name{CommonName: o.host},
+		DNSNames:     []string{o.host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	o.certDER, err = x509.CreateCertificate(rand.Reader, template, ca.root, csr.PublicKey, ca.rootKey)
+	return err
+}
+
+// fakeDomains is the domain storage AllowCertificate consults
+type fakeDomains map[string]*models.Domain
+
+func (f fakeDomains) FindDomainByHost(ctx context.Context, host string) (*models.Domain, error) {
+	if d, ok := f[host]; ok {
+		return d, nil
+	}
+	return nil, models.ErrDomainNotFound
+}
+
+func (f fakeDomains) ListDomains(ctx context.Context) ([]*models.Domain, error) { return nil, nil }
+func (f fakeDomains) FindDomainByID(ctx context.Context, id int64) (*models.Domain, error) {
+	return nil, models.ErrDomainNotFound
+}
+func (f fakeDomains) CreateDomain(ctx context.Context, domain *models.Domain) error { return nil }
+func (f fakeDomains) RegisterPendingDomain(ctx context.Context, domain *models.Domain, pendingTTL time.Duration) error {
+	return nil
+}
+func (f fakeDomains) MarkDomainVerified(ctx context.Context, id int64, token string) error {
+	return nil
+}
+func (f fakeDomains) SetDomainActive(ctx context.Context, id int64, active bool) error { return nil }
+
+// newTestDomainService serves short.example by default, with a verified and a pending customer domain
+func newTestDomainService() *service.DomainService {
+	verifiedAt := time.Now()
+	domains := fakeDomains{
+		"links.customer.example": {ID: 1, Host: "links.customer.example", Active: true, VerifiedAt: &verifiedAt},
+		"pending.example":        {ID: 2, Host: "pending.example", Active: true},
+	}
+	return service.NewDomainService(domains, nil, nil, &config.Config{BaseURL: "https://short.example"})
+}
+
+// newTestManager creates a manager issuing from ca into cache, for the hosts the domain service allows
+func newTestManager(t *testing.T, ca *testCA, cache autocert.Cache) *autocert.Manager {
+	t.Helper()
+	manager, err := NewManager(Options{
+		Email:        "ops@short.example",
+		DirectoryURL: ca.URL + "/directory",
+		CAFile:       ca.caFile(),
+	}, cache, newTestDomainService().AllowCertificate)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	ca.manager = manager
+	return manager
+}
+
+// hello is a client hello for host from a client supporting ECDSA certificates
+func hello(host string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:   host,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+}
+
+// testIssuance requests certificates for the default and a verified domain, checks that they were
+// stored in cache, and that hosts the domain service doesn't allow get none
+func testIssuance(t *testing.T, cache autocert.Cache) {
+	ca := newTestCA(t)
+	manager := newTestManager(t, ca, cache)
+
+	for _, host := range []string{"short.example", "links.customer.example"} {
+		cert, err := manager.GetCertificate(hello(host))
+		if err != nil {
+			t.Fatalf("GetCertificate(%s): %v", host, err)
+		}
+		if err := cert.Leaf.VerifyHostname(host); err != nil {
+			t.Errorf("certificate for %s: %v", host, err)
+		}
+		if cert.Leaf.Issuer.CommonName != ca.root.Subject.CommonName {
+			t.Errorf("certificate for %s issued by %q", host, cert.Leaf.Issuer.CommonName)
+		}
+		if _, err := cache.Get(context.Background(), host); err != nil {
+			t.Errorf("cache.Get(%s): %v", host, err)
+		}
+	}
+
+	for host, want := range map[string]error{
+		"pending.example": models.ErrDomainUnverified,
+		"unknown.example": models.ErrDomainNotFound,
+	} {
+		if _, err := manager.GetCertificate(hello(host)); !errors.Is(err, want) {
+			t.Errorf("GetCertificate(%s) error = %v, want %v", host, err, want)
+		}
+	}
+	if got := ca.issued(); len(got) != 2 {
+		t.Errorf("CA issued certificates for %v, want only the allowed hosts", got)
+	}
+
+	// Another replica sharing the cache serves the stored certificate without asking the CA
+	replica := newTestManager(t, ca, cache)
+	if _, err := replica.GetCertificate(hello("short.example")); err != nil {
+		t.Fatalf("replica GetCertificate: %v", err)
+	}
+	if got := ca.issued(); len(got) != 2 {
+		t.Errorf("CA issued certificates for %v after a cached request", got)
+	}
+}
+
+func TestManagerIssuesCertificates(t *testing.T) {
+	testIssuance(t, autocert.DirCache(t.TempDir()))
+}
+
+// TestManagerIssuesCertificatesIntoCertCache runs against the migrated Postgres database in
+// TEST_DATABASE_URL, and is skipped without one
+func TestManagerIssuesCertificatesIntoCertCache(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	reset := func() {
+		if _, err := db.Exec(`DELETE FROM acme_cache`); err != nil {
+			t.Fatalf("clear acme_cache: %v", err)
+		}
+	}
+	reset()
+	t.Cleanup(reset)
+
+	testIssuance(t, postgres.NewCertCache(db))
+}
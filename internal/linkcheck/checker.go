@@ -0,0 +1,253 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/policy"
+)
+
+// Store loads links due for a check and records the outcome
+type Store interface {
+	ListURLsDueForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*models.URL, error)
+	RecordHealthCheck(ctx context.Context, health *models.URLHealth, failed bool) error
+}
+
+// Notifier is told when a link starts failing
+type Notifier interface {
+	LinkBroken(ctx context.Context, url *models.URL, health *models.URLHealth)
+}
+
+// Options configures a Checker
+type Options struct {
+	Interval         time.Duration
+	BatchSize        int
+	Concurrency      int
+	PerHostLimit     int
+	PoliteDelay      time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
+	UserAgent        string
+	// AllowPrivate skips the SSRF address check; only meant for local testing
+	AllowPrivate bool
+}
+
+// Checker periodically probes active destinations and records their health
+type Checker struct {
+	store    Store
+	notifier Notifier
+	client   *http.Client
+	opts     Options
+	hosts    *hostLimiter
+}
+
+// NewChecker creates a Checker. A nil notifier disables broken-link events.
+func NewChecker(store Store, notifier Notifier, opts Options) *Checker {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 200
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.PerHostLimit <= 0 {
+		opts.PerHostLimit = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "Ziply-LinkCheck/1.0"
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = policy.DialControl
+	}
+
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   opts.Timeout,
+			ResponseHeaderTimeout: opts.Timeout,
+			MaxIdleConnsPerHost:   opts.PerHostLimit,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+
+	return &Checker{
+		store:    store,
+		notifier: notifier,
+		client:   client,
+		opts:     opts,
+		hosts:    newHostLimiter(opts.PerHostLimit, opts.PoliteDelay),
+	}
+}
+
+// Run checks due links every interval until ctx is canceled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDue checks every link not probed within the last interval, one batch at a time.
+// Links whose result couldn't be recorded stay due, so a batch with failed writes ends the
+// pass until the next tick instead of listing and probing the same links again right away.
+func (c *Checker) checkDue(ctx context.Context) {
+	cutoff := time.Now().Add(-c.opts.Interval)
+
+	for ctx.Err() == nil {
+		urls, err := c.store.ListURLsDueForCheck(ctx, cutoff, c.opts.BatchSize)
+		if err != nil {
+			log.Printf("Failed to list links for health check: %v", err)
+			return
+		}
+		if len(urls) == 0 {
+			return
+		}
+		c.hosts.sweep()
+
+		sem := make(chan struct{}, c.opts.Concurrency)
+		var wg sync.WaitGroup
+		var writeFailed atomic.Bool
+		for _, u := range urls {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(u *models.URL) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := c.checkOne(ctx, u); err != nil {
+					writeFailed.Store(true)
+				}
+			}(u)
+		}
+		wg.Wait()
+
+		if writeFailed.Load() || len(urls) < c.opts.BatchSize {
+			return
+		}
+	}
+}
+
+// checkOne probes a single link and records the result. It returns the error from
+// recording it; a probe cut short by shutdown records nothing.
+func (c *Checker) checkOne(ctx context.Context, u *models.URL) error {
+	host := hostOf(u.OriginalURL)
+	if err := c.hosts.acquire(ctx, host); err != nil {
+		return nil
+	}
+	health := c.probe(ctx, u.OriginalURL)
+	c.hosts.release(host)
+
+	if ctx.Err() != nil {
+		// Don't count our own shutdown as a failure
+		return nil
+	}
+
+	health.URLID = u.ID
+	failed := isFailure(health)
+	if err := c.store.RecordHealthCheck(ctx, health, failed); err != nil {
+		log.Printf("Failed to record health check for URL %d: %v", u.ID, err)
+		return err
+	}
+
+	// Fire exactly once, on the check that crosses the threshold
+	if failed && health.ConsecutiveFailures == c.opts.FailureThreshold && c.notifier != nil {
+		c.notifier.LinkBroken(ctx, u, health)
+	}
+	return nil
+}
+
+// probe sends a HEAD request, falling back to GET for servers that don't support HEAD
+func (c *Checker) probe(ctx context.Context, rawURL string) *models.URLHealth {
+	start := time.Now()
+	resp, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		resp.Body.Close()
+		start = time.Now()
+		resp, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+
+	health := &models.URLHealth{
+		LatencyMS:     time.Since(start).Milliseconds(),
+		LastCheckedAt: time.Now(),
+	}
+	if err != nil {
+		msg := err.Error()
+		health.LastError = &msg
+		return health
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	health.StatusCode = resp.StatusCode
+	finalURL := resp.Request.URL.String()
+	health.FinalURL = &finalURL
+	if resp.StatusCode >= 400 {
+		msg := fmt.Sprintf("destination returned status %d", resp.StatusCode)
+		health.LastError = &msg
+	}
+
+	return health
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+	return c.client.Do(req)
+}
+
+// isFailure reports whether a check counts towards a link being broken.
+// Rate limiting says nothing about the link itself, so 429 is not a failure.
+func isFailure(h *models.URLHealth) bool {
+	if h.StatusCode == http.StatusTooManyRequests {
+		return false
+	}
+	return h.StatusCode == 0 || h.StatusCode >= 400
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
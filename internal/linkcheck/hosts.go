@@ -0,0 +1,102 @@
+package linkcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter caps concurrent requests per host and spaces consecutive requests to the same host
+type hostLimiter struct {
+	limit int
+	delay time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+	next  time.Time
+	users int
+}
+
+func newHostLimiter(limit int, delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		delay: delay,
+		hosts: make(map[string]*hostState),
+	}
+}
+
+// acquire blocks until a request to host may start
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	l.mu.Lock()
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{slots: make(chan struct{}, l.limit)}
+		l.hosts[host] = st
+	}
+	st.users++
+	l.mu.Unlock()
+
+	select {
+	case st.slots <- struct{}{}:
+	case <-ctx.Done():
+		l.forget(host)
+		return ctx.Err()
+	}
+
+	// Reserve the next politeness slot for this host
+	l.mu.Lock()
+	start := st.next
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	st.next = start.Add(l.delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			l.release(host)
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// release frees the slot taken by acquire
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	st := l.hosts[host]
+	l.mu.Unlock()
+
+	<-st.slots
+	l.forget(host)
+}
+
+// forget drops one user of a host's state
+func (l *hostLimiter) forget(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hosts[host].users--
+}
+
+// sweep drops idle hosts whose politeness delay has passed
+func (l *hostLimiter) sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for host, st := range l.hosts {
+		if st.users == 0 && now.After(st.next) {
+			delete(l.hosts, host)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package linkcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// WebhookNotifier posts a JSON event to a URL when a link starts failing
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LinkBroken sends a link.broken event
+func (n *WebhookNotifier) LinkBroken(ctx context.Context, url *models.URL, health *models.URLHealth) {
+	event := struct {
+		Event       string            `json:"event"`
+		URLID       int64             `json:"url_id"`
+		ShortCode   string            `json:"short_code"`
+		OriginalURL string            `json:"original_url"`
+		Health      *models.URLHealth `json:"health"`
+	}{
+		Event:       "link.broken",
+		URLID:       url.ID,
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+		Health:      health,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode link.broken event: %v", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to create link.broken webhook request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to send link.broken webhook: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("link.broken webhook returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,193 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/policy"
+)
+
+// newTestFetcher returns a Fetcher that may reach httptest servers on loopback
+func newTestFetcher(opts Options) *Fetcher {
+	opts.AllowPrivate = true
+	return NewFetcher(opts)
+}
+
+func TestFetchHTML(t *testing.T) {
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head>
+			<title>Fetched page</title>
+			<meta name="description" content="Served by the test">
+			<meta property="og:title" content="Fetched for sharing">
+			<link rel="icon" href="/icon.png">
+		</head><body></body></html>`))
+	}))
+	defer srv.Close()
+
+	meta, err := newTestFetcher(Options{UserAgent: "test-agent"}).Fetch(context.Background(), srv.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if meta.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", meta.StatusCode, http.StatusOK)
+	}
+	if got, want := deref(meta.Title), "Fetched page"; got != want {
+		t.Errorf("title = %q, want %q", got, want)
+	}
+	if got, want := deref(meta.Description), "Served by the test"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+	if got, want := deref(meta.OGTitle), "Fetched for sharing"; got != want {
+		t.Errorf("og:title = %q, want %q", got, want)
+	}
+	if got, want := deref(meta.FaviconURL), srv.URL+"/icon.png"; got != want {
+		t.Errorf("favicon = %q, want %q", got, want)
+	}
+	if userAgent != "test-agent" {
+		t.Errorf("User-Agent = %q, want %q", userAgent, "test-agent")
+	}
+}
+
+func TestFetchFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved/here", http.StatusFound)
+	})
+	mux.HandleFunc("/moved/here", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<head><title>Moved</title><link rel="icon" href="icon.png"></head>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	meta, err := newTestFetcher(Options{}).Fetch(context.Background(), srv.URL+"/start")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	if got, want := deref(meta.Title), "Moved"; got != want {
+		t.Errorf("title = %q, want %q", got, want)
+	}
+	// Relative references resolve against the page the redirects ended on
+	if got, want := deref(meta.FaviconURL), srv.URL+"/moved/icon.png"; got != want {
+		t.Errorf("favicon = %q, want %q", got, want)
+	}
+}
+
+func TestFetchStopsAfterMaxRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(Options{MaxRedirects: 3}).Fetch(context.Background(), srv.URL+"/")
+	if err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Fatalf("Fetch error = %v, want the redirect limit", err)
+	}
+}
+
+func TestFetchRejectsRedirectToOtherScheme(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(Options{}).Fetch(context.Background(), srv.URL+"/")
+	if err == nil || !strings.Contains(err.Error(), "unsupported scheme ftp") {
+		t.Fatalf("Fetch error = %v, want an unsupported scheme", err)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	const maxBytes = 1024
+	padding := "<!-- " + strings.Repeat("x", maxBytes) + " -->"
+
+	tests := []struct {
+		name      string
+		page      string
+		wantTitle string
+	}{
+		{"head within limit", "<head><title>Early</title></head>" + padding, "Early"},
+		{"head past limit", padding + "<head><title>Late</title></head>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(tt.page))
+			}))
+			defer srv.Close()
+
+			meta, err := newTestFetcher(Options{MaxBytes: maxBytes}).Fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if got := deref(meta.Title); got != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestFetchNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("<title>Not parsed</title>"))
+	}))
+	defer srv.Close()
+
+	meta, err := newTestFetcher(Options{}).Fetch(context.Background(), srv.URL+"/doc.pdf")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if meta.Title != nil {
+		t.Errorf("title = %q, want nil", *meta.Title)
+	}
+	if got, want := deref(meta.FaviconURL), srv.URL+"/favicon.ico"; got != want {
+		t.Errorf("favicon = %q, want %q", got, want)
+	}
+}
+
+func TestFetchErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	meta, err := newTestFetcher(Options{}).Fetch(context.Background(), srv.URL)
+	if err == nil {
+		t.Fatal("Fetch succeeded, want an error for a 404")
+	}
+	if meta == nil || meta.StatusCode != http.StatusNotFound {
+		t.Errorf("metadata = %+v, want status %d recorded", meta, http.StatusNotFound)
+	}
+}
+
+func TestFetchRejectsPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	// Without AllowPrivate the dialer refuses loopback, private and link-local addresses
+	fetcher := NewFetcher(Options{})
+	_, err := fetcher.Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, policy.ErrBlockedAddress) {
+		t.Fatalf("Fetch error = %v, want %v", err, policy.ErrBlockedAddress)
+	}
+}
+
+func TestFetchRejectsUnsupportedScheme(t *testing.T) {
+	_, err := newTestFetcher(Options{}).Fetch(context.Background(), "file:///etc/passwd")
+	if err == nil || !strings.Contains(err.Error(), "unsupported scheme file") {
+		t.Fatalf("Fetch error = %v, want an unsupported scheme", err)
+	}
+}
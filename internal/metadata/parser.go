@@ -0,0 +1,122 @@
+package metadata
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// maxFieldLen caps stored text fields so a hostile page can't bloat the table
+const maxFieldLen = 1000
+
+// parseHead tokenizes an HTML document until </head> and fills in the metadata fields
+func parseHead(r io.Reader, pageURL *url.URL, meta *models.URLMetadata) {
+	base := pageURL
+	z := html.NewTokenizer(r)
+
+	var (
+		title       strings.Builder
+		inTitle     bool
+		description string
+		og          = make(map[string]string)
+		icons       = make(map[string]string)
+	)
+
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Body {
+				break loop
+			}
+			if tag == atom.Title {
+				inTitle = true
+				continue
+			}
+			if !hasAttr {
+				continue
+			}
+
+			attrs := readAttrs(z)
+			switch tag {
+			case atom.Base:
+				if href := resolve(pageURL, attrs["href"]); href != "" {
+					base, _ = url.Parse(href)
+				}
+			case atom.Meta:
+				content := attrs["content"]
+				if prop := strings.ToLower(attrs["property"]); strings.HasPrefix(prop, "og:") {
+					if _, seen := og[prop]; !seen {
+						og[prop] = content
+					}
+				} else if strings.ToLower(attrs["name"]) == "description" && description == "" {
+					description = content
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if _, seen := icons[rel]; !seen && attrs["href"] != "" {
+						icons[rel] = attrs["href"]
+					}
+				}
+			}
+		}
+	}
+
+	meta.Title = clean(title.String())
+	meta.Description = clean(description)
+	meta.OGTitle = clean(og["og:title"])
+	meta.OGDescription = clean(og["og:description"])
+	meta.OGSiteName = clean(og["og:site_name"])
+	meta.OGImageURL = stringPtr(resolve(base, og["og:image"]))
+
+	favicon := "/favicon.ico"
+	for _, rel := range []string{"icon", "shortcut", "apple-touch-icon"} {
+		if href, ok := icons[rel]; ok {
+			favicon = href
+			break
+		}
+	}
+	meta.FaviconURL = stringPtr(resolve(base, favicon))
+}
+
+// readAttrs collects the attributes of the current tag with lowercased keys
+func readAttrs(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, val, more := z.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(val)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// clean collapses whitespace and truncates a text field, returning nil when empty
+func clean(s string) *string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxFieldLen {
+		s = strings.ToValidUTF8(s[:maxFieldLen], "")
+	}
+	return stringPtr(s)
+}
@@ -0,0 +1,135 @@
+package metadata
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+func parse(t *testing.T, pageURL, page string) *models.URLMetadata {
+	t.Helper()
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", pageURL, err)
+	}
+	meta := &models.URLMetadata{}
+	parseHead(strings.NewReader(page), u, meta)
+	return meta
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func TestParseHeadFields(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<title>
+		An   example
+		page
+	</title>
+	<meta name="Description" content="What the page is about">
+	<meta name="description" content="A second description">
+	<meta property="og:title" content="Example for sharing">
+	<meta property="OG:Description" content="Shared description">
+	<meta property="og:site_name" content="Example">
+	<meta property="og:image" content="/images/card.png">
+	<link rel="shortcut icon" href="/static/favicon.png">
+</head>
+<body><title>Not the title</title></body>
+</html>`
+
+	meta := parse(t, "https://example.com/articles/1", page)
+
+	tests := []struct {
+		field string
+		got   *string
+		want  string
+	}{
+		{"title", meta.Title, "An example page"},
+		{"description", meta.Description, "What the page is about"},
+		{"og:title", meta.OGTitle, "Example for sharing"},
+		{"og:description", meta.OGDescription, "Shared description"},
+		{"og:site_name", meta.OGSiteName, "Example"},
+		{"og:image", meta.OGImageURL, "https://example.com/images/card.png"},
+		{"favicon", meta.FaviconURL, "https://example.com/static/favicon.png"},
+	}
+	for _, tt := range tests {
+		if got := deref(tt.got); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeadBase(t *testing.T) {
+	page := `<head>
+		<base href="https://cdn.example.net/assets/">
+		<meta property="og:image" content="card.png">
+		<link rel="icon" href="icon.svg">
+	</head>`
+
+	meta := parse(t, "https://example.com/page", page)
+
+	if got, want := deref(meta.OGImageURL), "https://cdn.example.net/assets/card.png"; got != want {
+		t.Errorf("og:image = %q, want %q", got, want)
+	}
+	if got, want := deref(meta.FaviconURL), "https://cdn.example.net/assets/icon.svg"; got != want {
+		t.Errorf("favicon = %q, want %q", got, want)
+	}
+}
+
+func TestParseHeadDefaults(t *testing.T) {
+	meta := parse(t, "http://example.com:8080/a/b", `<html><head></head><body>Hello</body></html>`)
+
+	for field, got := range map[string]*string{
+		"title":          meta.Title,
+		"description":    meta.Description,
+		"og:title":       meta.OGTitle,
+		"og:description": meta.OGDescription,
+		"og:site_name":   meta.OGSiteName,
+		"og:image":       meta.OGImageURL,
+	} {
+		if got != nil {
+			t.Errorf("%s = %q, want nil", field, *got)
+		}
+	}
+	if got, want := deref(meta.FaviconURL), "http://example.com:8080/favicon.ico"; got != want {
+		t.Errorf("favicon = %q, want %q", got, want)
+	}
+}
+
+func TestParseHeadRejectsUnsafeURLs(t *testing.T) {
+	page := `<head>
+		<meta property="og:image" content="javascript:alert(1)">
+		<link rel="icon" href="data:image/png;base64,AAAA">
+	</head>`
+
+	meta := parse(t, "https://example.com/", page)
+
+	if meta.OGImageURL != nil {
+		t.Errorf("og:image = %q, want nil", *meta.OGImageURL)
+	}
+	// An unusable icon reference leaves no favicon rather than falling back
+	if meta.FaviconURL != nil {
+		t.Errorf("favicon = %q, want nil", *meta.FaviconURL)
+	}
+}
+
+func TestParseHeadTruncatesLongFields(t *testing.T) {
+	long := strings.Repeat("é", maxFieldLen)
+	meta := parse(t, "https://example.com/", "<title>"+long+"</title>")
+
+	title := deref(meta.Title)
+	if len(title) > maxFieldLen {
+		t.Errorf("title is %d bytes, want at most %d", len(title), maxFieldLen)
+	}
+	if !strings.HasPrefix(long, title) {
+		t.Errorf("title was not truncated on a character boundary")
+	}
+}
@@ -0,0 +1,97 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// Store persists fetched metadata
+type Store interface {
+	SaveMetadata(ctx context.Context, meta *models.URLMetadata) error
+}
+
+type job struct {
+	urlID  int64
+	rawURL string
+}
+
+// Worker fetches metadata in the background so link creation never waits on the destination
+type Worker struct {
+	fetcher *Fetcher
+	store   Store
+	jobs    chan job
+	workers int
+}
+
+// NewWorker creates a Worker with the given concurrency and queue size
+func NewWorker(fetcher *Fetcher, store Store, workers, queueSize int) *Worker {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Worker{
+		fetcher: fetcher,
+		store:   store,
+		jobs:    make(chan job, queueSize),
+		workers: workers,
+	}
+}
+
+// Enqueue schedules a metadata fetch. It never blocks; when the queue is full the job is dropped.
+func (w *Worker) Enqueue(urlID int64, rawURL string) {
+	select {
+	case w.jobs <- job{urlID: urlID, rawURL: rawURL}:
+	default:
+		log.Printf("Metadata queue full, skipping URL %d", urlID)
+	}
+}
+
+// Check reports the queue as unhealthy once it is full and new jobs are being dropped
+func (w *Worker) Check(ctx context.Context) error {
+	if len(w.jobs) >= cap(w.jobs) {
+		return fmt.Errorf("metadata queue full (%d jobs)", len(w.jobs))
+	}
+	return nil
+}
+
+// Run processes jobs until ctx is canceled
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-w.jobs:
+					w.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) process(ctx context.Context, j job) {
+	meta, err := w.fetcher.Fetch(ctx, j.rawURL)
+	if meta == nil {
+		meta = &models.URLMetadata{}
+	}
+	meta.URLID = j.urlID
+	meta.SourceURL = j.rawURL
+	meta.FetchedAt = time.Now()
+	if err != nil {
+		msg := err.Error()
+		meta.FetchError = &msg
+	}
+
+	if err := w.store.SaveMetadata(ctx, meta); err != nil {
+		log.Printf("Failed to save metadata for URL %d: %v", j.urlID, err)
+	}
+}
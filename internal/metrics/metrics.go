@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types understood by Prometheus
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Sample is a single value of a metric, optionally labelled
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Metric is read on every scrape through Collect
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Collect func() []Sample
+}
+
+// Registry holds metrics and serves them in the Prometheus text format
+type Registry struct {
+	mu      sync.RWMutex
+	metrics []Metric
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a metric to the registry
+func (r *Registry) Register(m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Gauge registers an unlabelled gauge read from fn
+func (r *Registry) Gauge(name, help string, fn func() float64) {
+	r.Register(Metric{
+		Name: name,
+		Help: help,
+		Type: TypeGauge,
+		Collect: func() []Sample {
+			return []Sample{{Value: fn()}}
+		},
+	})
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := append([]Metric(nil), r.metrics...)
+	r.mu.RUnlock()
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.Name, m.Help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", m.Name, m.Type)
+		for _, s := range m.Collect() {
+			b.WriteString(m.Name)
+			writeLabels(&b, s.Labels)
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+			b.WriteByte('\n')
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeLabels writes labels sorted by name so output is stable between scrapes
+func writeLabels(b *strings.Builder, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, "%s=%q", name, labels[name])
+	}
+	b.WriteByte('}')
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// AuditAction is a kind of change recorded in the audit log
+type AuditAction string
+
+// Audited changes to links
+const (
+	AuditURLCreated       AuditAction = "url.created"
+	AuditURLUpdated       AuditAction = "url.updated"
+	AuditURLDeleted       AuditAction = "url.deleted"
+	AuditURLUndeleted     AuditAction = "url.undeleted"
+	AuditURLPurged        AuditAction = "url.purged"
+	AuditURLStatusChanged AuditAction = "url.status_changed"
+	AuditAliasAdded       AuditAction = "url.alias_added"
+	AuditAliasRenamed     AuditAction = "url.alias_renamed"
+	AuditAliasRemoved     AuditAction = "url.alias_removed"
+	AuditURLRestored      AuditAction = "url.restored"
+)
+
+// Valid reports whether the action is one that gets audited
+func (a AuditAction) Valid() bool {
+	switch a {
+	case AuditURLCreated, AuditURLUpdated, AuditURLDeleted, AuditURLUndeleted, AuditURLPurged, AuditURLStatusChanged,
+		AuditAliasAdded, AuditAliasRenamed, AuditAliasRemoved, AuditURLRestored:
+		return true
+	}
+	return false
+}
+
+// AuditActorType is the kind of actor that made a change
+type AuditActorType string
+
+// Audit actor types; system changes are made by the service itself, such as flagging a link the policy now blocks
+// or purging the trash
+const (
+	AuditActorUser      AuditActorType = "user"
+	AuditActorAdmin     AuditActorType = "admin"
+	AuditActorAnonymous AuditActorType = "anonymous"
+	AuditActorSystem    AuditActorType = "system"
+)
+
+// Valid reports whether the actor type is one of the known kinds
+func (t AuditActorType) Valid() bool {
+	switch t {
+	case AuditActorUser, AuditActorAdmin, AuditActorAnonymous, AuditActorSystem:
+		return true
+	}
+	return false
+}
+
+// AuditEntry records one change to a link. Before and After are snapshots of the link on
+// either side of the change: Before is nil for creations and After for purges.
+type AuditEntry struct {
+	ID        int64          `db:"id" json:"id"`
+	Action    AuditAction    `db:"action" json:"action"`
+	URLID     int64          `db:"url_id" json:"url_id"`
+	ActorType AuditActorType `db:"actor_type" json:"actor_type"`
+	ActorID   *int64         `db:"actor_id" json:"actor_id,omitempty"`
+	IP        *string        `db:"ip" json:"ip,omitempty"`
+	RequestID *string        `db:"request_id" json:"request_id,omitempty"`
+	Before    *URL           `db:"-" json:"before"`
+	After     *URL           `db:"-" json:"after"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
+
+// NewAuditEntry starts an audit entry for an action taken by actor
+func NewAuditEntry(action AuditAction, actor Actor) *AuditEntry {
+	entry := &AuditEntry{Action: action, ActorID: actor.UserID()}
+	switch {
+	case actor.Admin:
+		entry.ActorType = AuditActorAdmin
+	case actor.User != nil:
+		entry.ActorType = AuditActorUser
+	default:
+		entry.ActorType = AuditActorAnonymous
+	}
+	if actor.IP != "" {
+		entry.IP = &actor.IP
+	}
+	if actor.RequestID != "" {
+		entry.RequestID = &actor.RequestID
+	}
+	return entry
+}
+
+// AuditFilter narrows down and pages through the audit log, newest first
+type AuditFilter struct {
+	URLID     int64
+	ActorID   int64
+	ActorType AuditActorType
+	Action    AuditAction
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// AuditListResponse is a page of audit entries
+type AuditListResponse struct {
+	Entries []*AuditEntry `json:"entries"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+// Audit errors
+var (
+	ErrInvalidAuditAction    = errors.New("invalid action")
+	ErrInvalidAuditActorType = errors.New("invalid actor type: must be user, admin, anonymous or system")
+)
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Domain is a short domain links can be created on. The deployment's own
+// BASE_URL is the default domain and has ID 0; it isn't stored in the table.
+// Customer domains belong to the user or workspace that registered them; those
+// added by an admin have no owner.
+type Domain struct {
+	ID                int64      `db:"id" json:"id"`
+	Host              string     `db:"host" json:"host"`
+	Scheme            string     `db:"scheme" json:"scheme"`
+	Active            bool       `db:"active" json:"active"`
+	OwnerUserID       *int64     `db:"owner_user_id" json:"owner_user_id,omitempty"`
+	OwnerWorkspaceID  *int64     `db:"owner_workspace_id" json:"workspace_id,omitempty"`
+	VerificationToken string     `db:"verification_token" json:"-"`
+	VerifiedAt        *time.Time `db:"verified_at" json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Verified reports whether the domain's ownership has been proven
+func (d *Domain) Verified() bool {
+	return d.ID == 0 || d.VerifiedAt != nil
+}
+
+// BaseURL returns the URL short links on this domain start with
+func (d *Domain) BaseURL() string {
+	return d.Scheme + "://" + d.Host
+}
+
+// Ref returns the value stored in urls.domain_id for this domain, nil for the default domain
+func (d *Domain) Ref() *int64 {
+	if d == nil || d.ID == 0 {
+		return nil
+	}
+	id := d.ID
+	return &id
+}
+
+// CreateDomainRequest represents the payload for registering a domain. WorkspaceID
+// registers it for a workspace instead of the signed-in user.
+type CreateDomainRequest struct {
+	Host        string `json:"host"`
+	Scheme      string `json:"scheme,omitempty"`
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
+}
+
+// DomainRegistration is returned when a customer registers a domain. Publishing the token
+// in either place proves ownership.
+type DomainRegistration struct {
+	Domain       *Domain `json:"domain"`
+	Token        string  `json:"token"`
+	TXTRecord    string  `json:"txt_record"`
+	TXTValue     string  `json:"txt_value"`
+	WellKnownURL string  `json:"well_known_url"`
+}
+
+// VerifyDomainRequest selects how ownership is checked: "dns" or "http"
+type VerifyDomainRequest struct {
+	Method string `json:"method"`
+}
+
+// UpdateDomainRequest represents the payload for enabling or disabling a domain
+type UpdateDomainRequest struct {
+	Active bool `json:"active"`
+}
+
+// Domain errors
+var (
+	ErrDomainNotFound      = errors.New("domain not found")
+	ErrDomainNotAllowed    = errors.New("domain is not available for new links")
+	ErrDuplicateDomain     = errors.New("domain already exists")
+	ErrDomainUnverified    = errors.New("domain ownership could not be verified")
+	ErrInvalidVerifyMethod = errors.New("invalid verification method: must be dns or http")
+	ErrInvalidDomain       = errors.New("invalid domain: host must be a hostname with an optional port and scheme http or https")
+)
@@ -1,62 +1,296 @@
-package models
-
-import (
-	"errors"
-	"time"
-)
-
-// URL represents a shortened URL in the system
-type URL struct {
-	ID          int64      `db:"id" json:"id"`
-	OriginalURL string     `db:"original_url" json:"original_url" validate:"required,url"`
-	ShortCode   string     `db:"short_code" json:"short_code"`
-	CustomAlias *string    `db:"custom_alias" json:"custom_alias,omitempty"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
-	UserIP      *string    `db:"user_ip" json:"user_ip,omitempty"`
-}
-
-// URLStats represents the analytics data for a URL
-type URLStats struct {
-	ClickCount int       `json:"click_count"`
-	LastClick  time.Time `json:"last_click"`
-}
-
-// CreateURLRequest represents the payload for creating a new shortened URL
-type CreateURLRequest struct {
-	OriginalURL string  `json:"original_url" validate:"required,url"`
-	CustomAlias *string `json:"custom_alias,omitempty" validate:"omitempty,min=3,max=50,alphanum"`
-	ExpiresIn   *int    `json:"expires_in,omitempty" validate:"omitempty,min=1"`
-}
-
-// CreateURLResponse represents the response for a create URL request
-type CreateURLResponse struct {
-	ShortURL    string     `json:"short_url"`
-	ShortCode   string     `json:"short_code"`
-	OriginalURL string     `json:"original_url"`
-	CustomAlias *string    `json:"custom_alias,omitempty"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
-}
-
-// URLDetailResponse represents the response for a URL details request
-type URLDetailResponse struct {
-	URL   *URL      `json:"url"`
-	Stats *URLStats `json:"stats,omitempty"`
-}
-
-// ClickEvent represents a click on a shortened URL
-type ClickEvent struct {
-	URLID      int64     `db:"url_id"`
-	AccessedAt time.Time `db:"accessed_at"`
-	Referer    *string   `db:"referer"`
-	UserAgent  *string   `db:"user_agent"`
-	IPAddress  *string   `db:"ip_address"`
-}
-
-// Common errors
-var (
-	ErrURLNotFound      = errors.New("url not found")
-	ErrInvalidShortCode = errors.New("invalid short code")
-	ErrURLExpired       = errors.New("url has expired")
-	ErrDuplicateAlias   = errors.New("custom alias already exists")
-)
\ No newline at end of file
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// URLStatus is the moderation state of a URL
+type URLStatus string
+
+// Moderation states; only active URLs redirect
+const (
+	StatusActive        URLStatus = "active"
+	StatusDisabled      URLStatus = "disabled"
+	StatusFlagged       URLStatus = "flagged"
+	StatusPendingReview URLStatus = "pending_review"
+)
+
+// Valid reports whether the status is one of the known moderation states
+func (s URLStatus) Valid() bool {
+	switch s {
+	case StatusActive, StatusDisabled, StatusFlagged, StatusPendingReview:
+		return true
+	}
+	return false
+}
+
+// URL represents a shortened URL in the system. A URL with DeletedAt set is in the trash:
+// it no longer redirects but keeps its short code and aliases until it's purged. Version is
+// the URL's current version in its history and its ETag.
+type URL struct {
+	ID              int64      `db:"id" json:"id"`
+	OriginalURL     string     `db:"original_url" json:"original_url" validate:"required,url"`
+	ShortCode       string     `db:"short_code" json:"short_code"`
+	DomainID        *int64     `db:"domain_id" json:"domain_id,omitempty"`
+	CustomAlias     *string    `db:"custom_alias" json:"custom_alias,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt       *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	UserIP          *string    `db:"user_ip" json:"user_ip,omitempty"`
+	UserID          *int64     `db:"user_id" json:"user_id,omitempty"`
+	WorkspaceID     *int64     `db:"workspace_id" json:"workspace_id,omitempty"`
+	AlwaysPreview   bool       `db:"always_preview" json:"always_preview"`
+	Status          URLStatus  `db:"status" json:"status"`
+	StatusReason    *string    `db:"status_reason" json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `db:"status_changed_at" json:"status_changed_at,omitempty"`
+	DeletedAt       *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	Version         int        `db:"version" json:"version"`
+}
+
+// Edit returns the URL's editable fields
+func (u *URL) Edit() URLEdit {
+	return URLEdit{
+		OriginalURL:   u.OriginalURL,
+		CustomAlias:   u.CustomAlias,
+		ExpiresAt:     u.ExpiresAt,
+		AlwaysPreview: u.AlwaysPreview,
+	}
+}
+
+// ExpiredAt reports whether the URL's expiry has passed at t
+func (u *URL) ExpiredAt(t time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(t)
+}
+
+// URLStats represents the analytics data for a URL
+type URLStats struct {
+	ClickCount int           `json:"click_count"`
+	LastClick  time.Time     `json:"last_click"`
+	Daily      []DailyClicks `json:"daily,omitempty"`
+}
+
+// DailyClicks is the number of clicks a URL got on one day (UTC)
+type DailyClicks struct {
+	Day    time.Time `db:"day" json:"day"`
+	Clicks int       `db:"clicks" json:"clicks"`
+}
+
+// StatsDays is how many days of daily clicks URL stats include
+const StatsDays = 14
+
+// CreateURLRequest represents the payload for creating a new shortened URL
+type CreateURLRequest struct {
+	OriginalURL   string  `json:"original_url" validate:"required,url"`
+	CustomAlias   *string `json:"custom_alias,omitempty" validate:"omitempty,min=3,max=50"`
+	ExpiresIn     *int    `json:"expires_in,omitempty" validate:"omitempty,min=1"`
+	AlwaysPreview bool    `json:"always_preview,omitempty"`
+	// Domain is the host of a registered short domain; empty uses the default domain
+	Domain string `json:"domain,omitempty"`
+	// WorkspaceID puts the link in a workspace instead of the creator's personal links
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
+}
+
+// URLEdit is the editable part of a URL: the body of a PUT, which replaces all of it, and
+// the document a PATCH merge patch applies to. A custom alias renames the URL's primary
+// alias; a null or empty one removes it, and a null expiry means the URL never expires.
+type URLEdit struct {
+	OriginalURL   string     `json:"original_url"`
+	CustomAlias   *string    `json:"custom_alias"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	AlwaysPreview bool       `json:"always_preview"`
+}
+
+// ReplaceURLRequest is the body of a PUT: a URLEdit whose expiry can instead be given, as
+// before PUT replaced the whole URL, as expires_in days from now. Zero or less means none.
+type ReplaceURLRequest struct {
+	URLEdit
+	ExpiresIn *int `json:"expires_in,omitempty"`
+}
+
+// Edit returns the editable fields the request sets, with expires_in turned into an expiry time
+func (r *ReplaceURLRequest) Edit(now time.Time) (URLEdit, error) {
+	edit := r.URLEdit
+	if r.ExpiresIn == nil {
+		return edit, nil
+	}
+	if edit.ExpiresAt != nil {
+		return edit, ErrConflictingExpiry
+	}
+	if *r.ExpiresIn > 0 {
+		exp := now.Add(time.Duration(*r.ExpiresIn) * 24 * time.Hour)
+		edit.ExpiresAt = &exp
+	}
+	return edit, nil
+}
+
+// CreateURLResponse represents the response for a create URL request
+type CreateURLResponse struct {
+	ShortURL      string     `json:"short_url"`
+	PreviewURL    string     `json:"preview_url"`
+	ShortCode     string     `json:"short_code"`
+	OriginalURL   string     `json:"original_url"`
+	CustomAlias   *string    `json:"custom_alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	AlwaysPreview bool       `json:"always_preview"`
+}
+
+// UpdateStatusRequest represents the payload for changing the moderation status of a URL
+type UpdateStatusRequest struct {
+	Status URLStatus `json:"status"`
+	Reason *string   `json:"reason,omitempty"`
+}
+
+// URLMetadata holds what was scraped from a URL's destination page
+type URLMetadata struct {
+	URLID         int64     `db:"url_id" json:"-"`
+	SourceURL     string    `db:"source_url" json:"source_url"`
+	Title         *string   `db:"title" json:"title,omitempty"`
+	Description   *string   `db:"description" json:"description,omitempty"`
+	OGTitle       *string   `db:"og_title" json:"og_title,omitempty"`
+	OGDescription *string   `db:"og_description" json:"og_description,omitempty"`
+	OGSiteName    *string   `db:"og_site_name" json:"og_site_name,omitempty"`
+	OGImageURL    *string   `db:"og_image_url" json:"og_image_url,omitempty"`
+	FaviconURL    *string   `db:"favicon_url" json:"favicon_url,omitempty"`
+	StatusCode    int       `db:"status_code" json:"status_code,omitempty"`
+	FetchError    *string   `db:"fetch_error" json:"fetch_error,omitempty"`
+	FetchedAt     time.Time `db:"fetched_at" json:"fetched_at"`
+}
+
+// URLHealth holds the result of the latest destination health check
+type URLHealth struct {
+	URLID               int64      `db:"url_id" json:"-"`
+	StatusCode          int        `db:"status_code" json:"status_code"`
+	FinalURL            *string    `db:"final_url" json:"final_url,omitempty"`
+	LatencyMS           int64      `db:"latency_ms" json:"latency_ms"`
+	ConsecutiveFailures int        `db:"consecutive_failures" json:"consecutive_failures"`
+	LastError           *string    `db:"last_error" json:"last_error,omitempty"`
+	LastCheckedAt       time.Time  `db:"last_checked_at" json:"last_checked_at"`
+	LastSuccessAt       *time.Time `db:"last_success_at" json:"last_success_at,omitempty"`
+}
+
+// URLDetailResponse represents the response for a URL details request
+type URLDetailResponse struct {
+	URL      *URL         `json:"url"`
+	ShortURL string       `json:"short_url,omitempty"`
+	Stats    *URLStats    `json:"stats,omitempty"`
+	Metadata *URLMetadata `json:"metadata,omitempty"`
+	Health   *URLHealth   `json:"health,omitempty"`
+	Aliases  []*URLAlias  `json:"aliases,omitempty"`
+}
+
+// Health filters for URL listings
+const (
+	HealthBroken  = "broken"
+	HealthHealthy = "healthy"
+)
+
+// URLFilter narrows a URL listing
+type URLFilter struct {
+	Status URLStatus
+	Health string
+	// UserIP limits the listing to links created anonymously from this address; admins only
+	UserIP string
+	// UserID limits the listing to the personal links, outside any workspace, of this user
+	UserID int64
+	// WorkspaceID limits the listing to the links of this workspace
+	WorkspaceID int64
+	// Deleted lists the links in the trash instead, most recently deleted first
+	Deleted bool
+	// MinFailures is the number of consecutive failed checks that makes a link broken
+	MinFailures int
+	Limit       int
+	Offset      int
+}
+
+// URLListResponse represents a page of URLs
+type URLListResponse struct {
+	URLs   []*URLDetailResponse `json:"urls"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// URLPreview represents what a visitor sees before following a link
+type URLPreview struct {
+	ShortCode     string    `json:"short_code"`
+	OriginalURL   string    `json:"original_url"`
+	Domain        string    `json:"domain"`
+	CreatedAt     time.Time `json:"created_at"`
+	ClickCount    int       `json:"click_count"`
+	AlwaysPreview bool      `json:"always_preview"`
+}
+
+// ClickEvent represents a click on a shortened URL
+type ClickEvent struct {
+	URLID      int64     `db:"url_id"`
+	AccessedAt time.Time `db:"accessed_at"`
+	Referer    *string   `db:"referer"`
+	UserAgent  *string   `db:"user_agent"`
+	IPAddress  *string   `db:"ip_address"`
+}
+
+// AliasAvailability reports whether a custom alias can be registered
+type AliasAvailability struct {
+	Alias     string `json:"alias"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// URLAlias is an additional name a URL answers on. Renamed aliases keep
+// redirecting until RedirectUntil.
+type URLAlias struct {
+	Alias         string     `db:"alias" json:"alias"`
+	URLID         int64      `db:"url_id" json:"url_id"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	RetiredAt     *time.Time `db:"retired_at" json:"retired_at,omitempty"`
+	RedirectUntil *time.Time `db:"redirect_until" json:"redirect_until,omitempty"`
+}
+
+// URLVersion is a saved state of a URL's editable fields. Version 1 is the URL as created;
+// every change to its destination, primary alias, expiry or preview setting adds the next one.
+type URLVersion struct {
+	URLID         int64      `db:"url_id" json:"url_id"`
+	Version       int        `db:"version" json:"version"`
+	OriginalURL   string     `db:"original_url" json:"original_url"`
+	CustomAlias   *string    `db:"custom_alias" json:"custom_alias,omitempty"`
+	ExpiresAt     *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	AlwaysPreview bool       `db:"always_preview" json:"always_preview"`
+	CreatedBy     *int64     `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Edit returns the editable fields as they were in this version
+func (v *URLVersion) Edit() URLEdit {
+	return URLEdit{
+		OriginalURL:   v.OriginalURL,
+		CustomAlias:   v.CustomAlias,
+		ExpiresAt:     v.ExpiresAt,
+		AlwaysPreview: v.AlwaysPreview,
+	}
+}
+
+// AliasRequest represents the payload for adding or renaming an alias
+type AliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// Common errors
+var (
+	ErrURLNotFound        = errors.New("url not found")
+	ErrInvalidShortCode   = errors.New("invalid short code")
+	ErrURLExpired         = errors.New("url has expired")
+	ErrDuplicateAlias     = errors.New("custom alias already exists")
+	ErrURLDisabled        = errors.New("url has been disabled")
+	ErrDestinationBlocked = errors.New("destination is not allowed")
+	ErrInvalidStatus      = errors.New("invalid url status")
+	ErrDuplicateShortCode = errors.New("short code already exists")
+	ErrShortCodeExhausted = errors.New("could not generate a unique short code")
+	ErrInvalidAlias       = errors.New("invalid custom alias: must be 3-50 letters, digits, hyphens or underscores")
+	ErrAliasNotFound      = errors.New("alias not found")
+	ErrAliasReserved      = errors.New("custom alias is reserved")
+	ErrAliasBlocked       = errors.New("custom alias contains a blocked word")
+	ErrVersionNotFound    = errors.New("version not found")
+	ErrVersionMismatch    = errors.New("url has changed since the version given")
+	ErrInvalidURL         = errors.New("invalid URL format")
+	ErrConflictingExpiry  = errors.New("give either expires_at or expires_in, not both")
+	ErrInvalidPatch       = errors.New("invalid merge patch: must be a JSON object of original_url, custom_alias, expires_at and always_preview")
+)
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// QuotaOwnerKind is what a quota is accounted to
+type QuotaOwnerKind string
+
+// Quota owners: links in a workspace count against the workspace, other links against the user who created them
+const (
+	OwnerWorkspace QuotaOwnerKind = "workspace"
+	OwnerUser      QuotaOwnerKind = "user"
+)
+
+// QuotaOwner is a workspace or user whose usage is metered
+type QuotaOwner struct {
+	Kind QuotaOwnerKind
+	ID   int64
+}
+
+// QuotaOwner returns who the link counts against. Links created anonymously aren't metered.
+func (u *URL) QuotaOwner() (QuotaOwner, bool) {
+	switch {
+	case u.WorkspaceID != nil:
+		return QuotaOwner{Kind: OwnerWorkspace, ID: *u.WorkspaceID}, true
+	case u.UserID != nil:
+		return QuotaOwner{Kind: OwnerUser, ID: *u.UserID}, true
+	}
+	return QuotaOwner{}, false
+}
+
+// QuotaLimits caps an owner's usage. Zero means unlimited.
+type QuotaLimits struct {
+	LinksPerMonth  int64 `json:"links_per_month"`
+	ActiveLinks    int64 `json:"active_links"`
+	ClicksPerMonth int64 `json:"clicks_per_month"`
+}
+
+// QuotaOverrides are a workspace's own limits; nil fields fall back to the configured defaults
+type QuotaOverrides struct {
+	LinksPerMonth  *int64 `db:"links_per_month" json:"links_per_month"`
+	ActiveLinks    *int64 `db:"active_links" json:"active_links"`
+	ClicksPerMonth *int64 `db:"clicks_per_month" json:"clicks_per_month"`
+}
+
+// Apply returns the limits with the overrides that are set replacing them
+func (o *QuotaOverrides) Apply(limits QuotaLimits) QuotaLimits {
+	if o.LinksPerMonth != nil {
+		limits.LinksPerMonth = *o.LinksPerMonth
+	}
+	if o.ActiveLinks != nil {
+		limits.ActiveLinks = *o.ActiveLinks
+	}
+	if o.ClicksPerMonth != nil {
+		limits.ClicksPerMonth = *o.ClicksPerMonth
+	}
+	return limits
+}
+
+// Usage is an owner's consumption in the current month against their limits
+type Usage struct {
+	// Period is the first day of the month being counted, in UTC
+	Period       time.Time   `db:"period" json:"period"`
+	ResetsAt     time.Time   `db:"-" json:"resets_at"`
+	LinksCreated int64       `db:"links_created" json:"links_created"`
+	ActiveLinks  int64       `db:"active_links" json:"active_links"`
+	Clicks       int64       `db:"clicks" json:"clicks"`
+	Limits       QuotaLimits `db:"-" json:"limits"`
+}
+
+// Quota errors
+var (
+	ErrLinkQuotaExceeded       = errors.New("monthly link quota exceeded")
+	ErrActiveLinkQuotaExceeded = errors.New("active link quota exceeded; delete or expire links to create more")
+	ErrClickQuotaExceeded      = errors.New("monthly click quota exceeded")
+	ErrInvalidQuota            = errors.New("quota limits can't be negative")
+)
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Role is what a user may do across the deployment
+type Role string
+
+// Roles, from least to most privileged
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+// Valid reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
+// Outranks reports whether r grants more than other
+func (r Role) Outranks(other Role) bool {
+	return r == RoleAdmin && other != RoleAdmin
+}
+
+// User is a person who signs in to the web UI. Users created through single sign-on
+// have no password and are identified by their issuer and subject.
+type User struct {
+	ID           int64     `db:"id" json:"id"`
+	Email        string    `db:"email" json:"email"`
+	PasswordHash string    `db:"password_hash" json:"-"`
+	Role         Role      `db:"role" json:"role"`
+	OIDCIssuer   *string   `db:"oidc_issuer" json:"-"`
+	OIDCSubject  *string   `db:"oidc_subject" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Identity is a user as asserted by an OpenID Connect provider. An empty Role leaves a
+// known user's role unchanged.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Role          Role
+}
+
+// Actor is who a request acts for: a signed-in user, an admin, or an anonymous visitor
+type Actor struct {
+	User *User
+	// Admin bypasses workspace and ownership checks
+	Admin bool
+	// IP and RequestID identify the request in the audit log
+	IP        string
+	RequestID string
+}
+
+// UserID returns the signed-in user's ID, or nil for anonymous actors
+func (a Actor) UserID() *int64 {
+	if a.User == nil {
+		return nil
+	}
+	return &a.User.ID
+}
+
+// Session is a signed-in browser. The cookie carries a random token; only its hash is stored.
+type Session struct {
+	TokenHash string    `db:"token_hash"`
+	UserID    int64     `db:"user_id"`
+	CSRFToken string    `db:"csrf_token"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// Credentials are what a user signs up or signs in with
+type Credentials struct {
+	Email    string
+	Password string
+}
+
+// User errors
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrDuplicateEmail     = errors.New("an account with this email already exists")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrWeakPassword       = errors.New("password must be between 10 and 128 characters")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrSessionNotFound    = errors.New("session not found or expired")
+	ErrRegistrationClosed = errors.New("registration is disabled")
+	ErrIdentityIncomplete = errors.New("identity provider did not return an email address")
+	ErrIdentityUnknown    = errors.New("sign in to the web UI once before using identity provider tokens")
+	ErrIdentityConflict   = errors.New("an account with this email already exists and is not linked to your identity provider")
+)
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// WorkspaceRole is what a member may do in a workspace
+type WorkspaceRole string
+
+// Workspace roles, from most to least privileged
+const (
+	WorkspaceOwner  WorkspaceRole = "owner"
+	WorkspaceAdmin  WorkspaceRole = "admin"
+	WorkspaceEditor WorkspaceRole = "editor"
+	WorkspaceViewer WorkspaceRole = "viewer"
+)
+
+// Permission is an action on a workspace or its links
+type Permission int
+
+// Permissions checked against workspace roles
+const (
+	// PermViewLinks allows listing links and reading their stats
+	PermViewLinks Permission = iota
+	// PermEditLinks allows creating, changing and deleting links
+	PermEditLinks
+	// PermManageMembers allows inviting members and changing or removing them
+	PermManageMembers
+	// PermManageDomains allows registering and verifying the workspace's own domains
+	PermManageDomains
+)
+
+// rank orders roles so they can be compared
+func (r WorkspaceRole) rank() int {
+	switch r {
+	case WorkspaceOwner:
+		return 4
+	case WorkspaceAdmin:
+		return 3
+	case WorkspaceEditor:
+		return 2
+	case WorkspaceViewer:
+		return 1
+	}
+	return 0
+}
+
+// Valid reports whether the role is one of the known workspace roles
+func (r WorkspaceRole) Valid() bool {
+	return r.rank() > 0
+}
+
+// AtLeast reports whether r is as privileged as other
+func (r WorkspaceRole) AtLeast(other WorkspaceRole) bool {
+	return r.rank() >= other.rank()
+}
+
+// Can reports whether the role grants a permission
+func (r WorkspaceRole) Can(p Permission) bool {
+	switch p {
+	case PermViewLinks:
+		return r.AtLeast(WorkspaceViewer)
+	case PermEditLinks:
+		return r.AtLeast(WorkspaceEditor)
+	case PermManageMembers, PermManageDomains:
+		return r.AtLeast(WorkspaceAdmin)
+	}
+	return false
+}
+
+// CanAssign reports whether a member with role r may give or take away role other.
+// Owners manage everyone; admins manage editors and viewers.
+func (r WorkspaceRole) CanAssign(other WorkspaceRole) bool {
+	if r == WorkspaceOwner {
+		return true
+	}
+	return r.Can(PermManageMembers) && !other.AtLeast(WorkspaceAdmin)
+}
+
+// Workspace is a team that owns links together
+type Workspace struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// Role is the caller's role in the workspace
+	Role WorkspaceRole `db:"role" json:"role,omitempty"`
+}
+
+// WorkspaceMember is a user's membership of a workspace
+type WorkspaceMember struct {
+	WorkspaceID int64         `db:"workspace_id" json:"workspace_id"`
+	UserID      int64         `db:"user_id" json:"user_id"`
+	Email       string        `db:"email" json:"email"`
+	Role        WorkspaceRole `db:"role" json:"role"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at"`
+}
+
+// Invitation asks someone to join a workspace. Only a hash of its token is stored.
+type Invitation struct {
+	ID          int64         `db:"id" json:"id"`
+	WorkspaceID int64         `db:"workspace_id" json:"workspace_id"`
+	Email       string        `db:"email" json:"email"`
+	Role        WorkspaceRole `db:"role" json:"role"`
+	TokenHash   string        `db:"token_hash" json:"-"`
+	InvitedBy   *int64        `db:"invited_by" json:"invited_by,omitempty"`
+	CreatedAt   time.Time     `db:"created_at" json:"created_at"`
+	ExpiresAt   time.Time     `db:"expires_at" json:"expires_at"`
+	AcceptedAt  *time.Time    `db:"accepted_at" json:"accepted_at,omitempty"`
+}
+
+// InvitationResponse is a new invitation with the token the invitee accepts it with
+type InvitationResponse struct {
+	*Invitation
+	Token     string `json:"token"`
+	AcceptURL string `json:"accept_url"`
+}
+
+// CreateWorkspaceRequest represents the payload for creating a workspace
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// InviteRequest represents the payload for inviting someone to a workspace
+type InviteRequest struct {
+	Email string        `json:"email"`
+	Role  WorkspaceRole `json:"role"`
+}
+
+// UpdateMemberRequest represents the payload for changing a member's role
+type UpdateMemberRequest struct {
+	Role WorkspaceRole `json:"role"`
+}
+
+// AcceptInvitationRequest represents the payload for joining a workspace
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// Workspace errors
+var (
+	ErrWorkspaceNotFound    = errors.New("workspace not found")
+	ErrInvalidWorkspaceName = errors.New("workspace name must be between 1 and 100 characters")
+	ErrInvalidWorkspaceRole = errors.New("invalid role: must be owner, admin, editor or viewer")
+	ErrForbidden            = errors.New("you don't have permission to do this")
+	ErrSignInRequired       = errors.New("sign in required")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrLastOwner            = errors.New("a workspace must keep at least one owner")
+	ErrInvitationNotFound   = errors.New("invitation not found or expired")
+	ErrInvitationMismatch   = errors.New("invitation was sent to a different email address")
+	ErrAlreadyMember        = errors.New("already a member of this workspace")
+)
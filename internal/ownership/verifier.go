@@ -0,0 +1,155 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/policy"
+)
+
+// Verification methods
+const (
+	MethodDNS  = "dns"
+	MethodHTTP = "http"
+)
+
+const (
+	txtPrefix      = "_ziply-verification."
+	txtValuePrefix = "ziply-verification="
+	wellKnownPath  = "/.well-known/ziply-verification.txt"
+	maxTokenBytes  = 1024
+	maxRedirects   = 3
+)
+
+// TXTRecord returns the DNS name the verification TXT record is published under
+func TXTRecord(host string) string {
+	return txtPrefix + hostname(host)
+}
+
+// TXTValue returns the TXT record value that proves ownership with token
+func TXTValue(token string) string {
+	return txtValuePrefix + token
+}
+
+// WellKnownURL returns the URL that must serve token to prove ownership over HTTP
+func WellKnownURL(host string) string {
+	return "http://" + host + wellKnownPath
+}
+
+// Options configures a Verifier
+type Options struct {
+	Timeout time.Duration
+	// AllowPrivate skips the SSRF address check for HTTP verification; only meant for local testing
+	AllowPrivate bool
+}
+
+// Verifier checks that a domain's owner has published a verification token
+type Verifier struct {
+	resolver *net.Resolver
+	client   *http.Client
+}
+
+// NewVerifier creates a Verifier that looks tokens up in DNS or fetches them over HTTP
+func NewVerifier(opts Options) *Verifier {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = policy.DialControl
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   opts.Timeout,
+			ResponseHeaderTimeout: opts.Timeout,
+		},
+		Timeout: opts.Timeout,
+		// Follow redirects on the same host only, e.g. to HTTPS
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Hostname() != via[0].URL.Hostname() {
+				return fmt.Errorf("redirect to another host %s", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+
+	return &Verifier{
+		resolver: net.DefaultResolver,
+		client:   client,
+	}
+}
+
+// Verify checks that token is published for host using method. A missing or wrong token
+// returns an error wrapping models.ErrDomainUnverified.
+func (v *Verifier) Verify(ctx context.Context, method, host, token string) error {
+	switch method {
+	case MethodDNS:
+		return v.verifyDNS(ctx, host, token)
+	case MethodHTTP:
+		return v.verifyHTTP(ctx, host, token)
+	default:
+		return models.ErrInvalidVerifyMethod
+	}
+}
+
+// verifyDNS looks for the token in the host's verification TXT record
+func (v *Verifier) verifyDNS(ctx context.Context, host, token string) error {
+	records, err := v.resolver.LookupTXT(ctx, TXTRecord(host))
+	if err != nil {
+		return fmt.Errorf("%w: TXT lookup for %s failed: %v", models.ErrDomainUnverified, TXTRecord(host), err)
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == TXTValue(token) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no TXT record %s with the expected value", models.ErrDomainUnverified, TXTRecord(host))
+}
+
+// verifyHTTP fetches the well-known token file from the host
+func (v *Verifier) verifyHTTP(ctx context.Context, host, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, WellKnownURL(host), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: fetching %s failed: %v", models.ErrDomainUnverified, WellKnownURL(host), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", models.ErrDomainUnverified, WellKnownURL(host), resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenBytes))
+	if err != nil {
+		return fmt.Errorf("%w: reading %s failed: %v", models.ErrDomainUnverified, WellKnownURL(host), err)
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return fmt.Errorf("%w: %s does not contain the expected token", models.ErrDomainUnverified, WellKnownURL(host))
+	}
+	return nil
+}
+
+// hostname strips any port from host
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,47 @@
+package policy
+
+import "strings"
+
+// DomainList matches host names against exact entries and "*." wildcard suffixes.
+// "example.com" matches only that host, "*.example.com" matches any of its subdomains.
+type DomainList struct {
+	exact    map[string]struct{}
+	suffixes []string
+}
+
+// NewDomainList builds a DomainList from configured entries
+func NewDomainList(entries []string) *DomainList {
+	l := &DomainList{exact: make(map[string]struct{})}
+
+	for _, entry := range entries {
+		entry = normalizeHost(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "*.") {
+			l.suffixes = append(l.suffixes, entry[1:])
+			continue
+		}
+		l.exact[entry] = struct{}{}
+	}
+
+	return l
+}
+
+// Empty reports whether the list has no entries
+func (l *DomainList) Empty() bool {
+	return len(l.exact) == 0 && len(l.suffixes) == 0
+}
+
+// Match reports whether a normalized host matches any entry
+func (l *DomainList) Match(host string) bool {
+	if _, ok := l.exact[host]; ok {
+		return true
+	}
+	for _, suffix := range l.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+// Rule names reported in a Violation
+const (
+	RuleInvalidURL   = "invalid_url"
+	RuleDenyList     = "deny_list"
+	RuleNotAllowed   = "not_allowed"
+	RuleThreatList   = "threat_list"
+	RulePrivateIP    = "private_ip"
+	RuleSelfRedirect = "self_redirect"
+	RuleUnresolvable = "unresolvable"
+)
+
+// Violation describes why a destination was rejected by the policy
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+// Error implements the error interface
+func (v *Violation) Error() string {
+	return fmt.Sprintf("destination blocked (%s): %s", v.Rule, v.Reason)
+}
+
+// Unwrap lets callers match a Violation with models.ErrDestinationBlocked
+func (v *Violation) Unwrap() error {
+	return models.ErrDestinationBlocked
+}
+
+// Resolver looks up the IP addresses of a host
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// HostOwner reports whether a host is one of the short domains this deployment serves
+type HostOwner interface {
+	OwnsHost(ctx context.Context, host string) (bool, error)
+}
+
+// Options configures an Engine
+type Options struct {
+	AllowDomains    []string
+	DenyDomains     []string
+	BlockPrivateIPs bool
+	OwnHosts        []string
+	// Domains is asked about hosts that aren't in OwnHosts, since customer domains are added at runtime
+	Domains        HostOwner
+	ThreatList     *ThreatList
+	Resolver       Resolver
+	ResolveTimeout time.Duration
+}
+
+// Engine decides whether a destination URL may be shortened or served
+type Engine struct {
+	allow           *DomainList
+	deny            *DomainList
+	blockPrivateIPs bool
+	ownHosts        map[string]struct{}
+	domains         HostOwner
+	threats         *ThreatList
+	resolver        Resolver
+	resolveTimeout  time.Duration
+}
+
+// NewEngine creates a new Engine
+func NewEngine(opts Options) *Engine {
+	e := &Engine{
+		allow:           NewDomainList(opts.AllowDomains),
+		deny:            NewDomainList(opts.DenyDomains),
+		blockPrivateIPs: opts.BlockPrivateIPs,
+		ownHosts:        make(map[string]struct{}),
+		domains:         opts.Domains,
+		threats:         opts.ThreatList,
+		resolver:        opts.Resolver,
+		resolveTimeout:  opts.ResolveTimeout,
+	}
+
+	for _, h := range opts.OwnHosts {
+		if h = normalizeHost(h); h != "" {
+			e.ownHosts[h] = struct{}{}
+		}
+	}
+
+	if e.resolver == nil {
+		e.resolver = net.DefaultResolver
+	}
+	if e.resolveTimeout <= 0 {
+		e.resolveTimeout = 2 * time.Second
+	}
+
+	return e
+}
+
+// Check evaluates a destination, resolving its host to catch names that point at private addresses.
+// It is meant for create and update, where a DNS lookup is affordable.
+func (e *Engine) Check(ctx context.Context, rawURL string) error {
+	return e.evaluate(ctx, rawURL, true)
+}
+
+// CheckStatic evaluates a destination without any network access.
+// It is meant for the redirect path, which must stay fast.
+func (e *Engine) CheckStatic(ctx context.Context, rawURL string) error {
+	return e.evaluate(ctx, rawURL, false)
+}
+
+func (e *Engine) evaluate(ctx context.Context, rawURL string, resolve bool) error {
+	// Only web destinations; javascript:, data: and the like would run in or leave the browser
+	if err := utils.ValidateURL(rawURL); err != nil {
+		return &Violation{Rule: RuleInvalidURL, Reason: err.Error()}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return &Violation{Rule: RuleInvalidURL, Reason: "destination has no host"}
+	}
+	host := normalizeHost(u.Hostname())
+
+	// Browsers read hosts such as 2130706433, 0x7f000001 and 127.1 as IPv4 addresses, which
+	// would slip past the deny list and the address checks
+	if ip, ok := parseIPv4Number(host); ok && ip.String() != host {
+		return &Violation{Rule: RuleInvalidURL, Reason: fmt.Sprintf("destination address must be written as %s", ip)}
+	}
+
+	// Links that point back at us would loop forever. Registered domains are only looked up
+	// when resolving is allowed, keeping the database off the redirect path.
+	if _, ok := e.ownHosts[host]; ok {
+		return &Violation{Rule: RuleSelfRedirect, Reason: "destination points back to this shortener"}
+	}
+	if resolve && e.domains != nil {
+		own, err := e.domains.OwnsHost(ctx, host)
+		if err != nil {
+			return err
+		}
+		if own {
+			return &Violation{Rule: RuleSelfRedirect, Reason: "destination points back to this shortener"}
+		}
+	}
+
+	if e.deny.Match(host) {
+		return &Violation{Rule: RuleDenyList, Reason: fmt.Sprintf("domain %s is blocked", host)}
+	}
+
+	if !e.allow.Empty() && !e.allow.Match(host) {
+		return &Violation{Rule: RuleNotAllowed, Reason: fmt.Sprintf("domain %s is not on the allow list", host)}
+	}
+
+	if e.threats != nil && e.threats.Match(u) {
+		return &Violation{Rule: RuleThreatList, Reason: "destination matches a known threat list entry"}
+	}
+
+	if e.blockPrivateIPs {
+		if err := e.checkAddress(ctx, host, resolve); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAddress rejects hosts that are, or resolve to, non-public addresses
+func (e *Engine) checkAddress(ctx context.Context, host string, resolve bool) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &Violation{Rule: RulePrivateIP, Reason: "destination is a loopback host"}
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if IsBlockedIP(ip) {
+			return &Violation{Rule: RulePrivateIP, Reason: fmt.Sprintf("destination address %s is not public", ip)}
+		}
+		return nil
+	}
+
+	if !resolve {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.resolveTimeout)
+	defer cancel()
+
+	addrs, err := e.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		// Whatever the lookup missed, a browser or a later lookup might still resolve
+		return &Violation{Rule: RuleUnresolvable, Reason: fmt.Sprintf("destination %s could not be resolved", host)}
+	}
+	for _, addr := range addrs {
+		if IsBlockedIP(addr.IP) {
+			return &Violation{Rule: RulePrivateIP, Reason: fmt.Sprintf("destination %s resolves to non-public address %s", host, addr.IP)}
+		}
+	}
+
+	return nil
+}
+
+// normalizeHost lowercases a host name and strips any port and trailing dot
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,346 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// updateFile mirrors the threatListUpdates:fetch response of the Safe Browsing v4 Update API
+type updateFile struct {
+	ListUpdateResponses []listUpdate `json:"listUpdateResponses"`
+}
+
+type listUpdate struct {
+	ThreatType      string        `json:"threatType"`
+	PlatformType    string        `json:"platformType"`
+	ThreatEntryType string        `json:"threatEntryType"`
+	ResponseType    string        `json:"responseType"`
+	Additions       []threatEntry `json:"additions"`
+	Removals        []threatEntry `json:"removals"`
+	Checksum        *listChecksum `json:"checksum"`
+}
+
+type threatEntry struct {
+	CompressionType string      `json:"compressionType"`
+	RawHashes       *rawHashes  `json:"rawHashes"`
+	RawIndices      *rawIndices `json:"rawIndices"`
+}
+
+type rawHashes struct {
+	PrefixSize int    `json:"prefixSize"`
+	RawHashes  string `json:"rawHashes"`
+}
+
+type rawIndices struct {
+	Indices []int `json:"indices"`
+}
+
+type listChecksum struct {
+	SHA256 string `json:"sha256"`
+}
+
+// listKey identifies one threat list; updates to the same list build on each other
+type listKey struct {
+	threatType      string
+	platformType    string
+	threatEntryType string
+}
+
+// ThreatList holds SHA-256 hash prefixes of known malicious URL expressions
+type ThreatList struct {
+	// prefixes are keyed by prefix length so a lookup only slices each full hash once per size
+	prefixes map[int]map[string]struct{}
+	count    int
+}
+
+// LoadThreatList reads a threat list file in the Safe Browsing v4 update format. Each list,
+// identified by its threat, platform and entry type, is built up in file order: a full update
+// replaces it, and a partial update removes entries by their index in the list so far before
+// merging in its additions. Checksums are verified over the list that results.
+// Only RAW compression is supported.
+func LoadThreatList(path string) (*ThreatList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read threat list: %w", err)
+	}
+
+	var file updateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse threat list: %w", err)
+	}
+
+	lists := make(map[listKey][]string)
+	for _, update := range file.ListUpdateResponses {
+		if update.ThreatEntryType != "" && update.ThreatEntryType != "URL" {
+			continue
+		}
+
+		key := listKey{update.ThreatType, update.PlatformType, update.ThreatEntryType}
+		entries, err := applyUpdate(lists[key], update)
+		if err != nil {
+			return nil, fmt.Errorf("threat list %s: %w", update.ThreatType, err)
+		}
+		lists[key] = entries
+	}
+
+	list := &ThreatList{prefixes: make(map[int]map[string]struct{})}
+	for _, entries := range lists {
+		for _, p := range entries {
+			set, ok := list.prefixes[len(p)]
+			if !ok {
+				set = make(map[string]struct{})
+				list.prefixes[len(p)] = set
+			}
+			if _, dup := set[p]; !dup {
+				set[p] = struct{}{}
+				list.count++
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// applyUpdate returns the sorted prefixes of a list after an update to its current ones
+func applyUpdate(current []string, update listUpdate) ([]string, error) {
+	var prefixes []string
+	switch update.ResponseType {
+	case "", "FULL_UPDATE":
+	case "PARTIAL_UPDATE":
+		prefixes = append(prefixes, current...)
+	default:
+		return nil, fmt.Errorf("unsupported response type %s", update.ResponseType)
+	}
+
+	// Removal indices refer to positions in the sorted list before this update's additions
+	for _, removal := range update.Removals {
+		if removal.CompressionType != "" && removal.CompressionType != "RAW" {
+			return nil, fmt.Errorf("unsupported compression type %s", removal.CompressionType)
+		}
+		if removal.RawIndices == nil {
+			continue
+		}
+		drop := make(map[int]struct{}, len(removal.RawIndices.Indices))
+		for _, idx := range removal.RawIndices.Indices {
+			if idx < 0 || idx >= len(prefixes) {
+				return nil, fmt.Errorf("removal index %d out of range", idx)
+			}
+			drop[idx] = struct{}{}
+		}
+		kept := prefixes[:0]
+		for i, p := range prefixes {
+			if _, ok := drop[i]; !ok {
+				kept = append(kept, p)
+			}
+		}
+		prefixes = kept
+	}
+
+	for _, add := range update.Additions {
+		if add.CompressionType != "" && add.CompressionType != "RAW" {
+			return nil, fmt.Errorf("unsupported compression type %s", add.CompressionType)
+		}
+		if add.RawHashes == nil {
+			continue
+		}
+
+		size := add.RawHashes.PrefixSize
+		if size < 4 || size > sha256.Size {
+			return nil, fmt.Errorf("invalid prefix size %d", size)
+		}
+		raw, err := base64.StdEncoding.DecodeString(add.RawHashes.RawHashes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw hashes: %w", err)
+		}
+		if len(raw)%size != 0 {
+			return nil, fmt.Errorf("raw hashes length %d is not a multiple of prefix size %d", len(raw), size)
+		}
+		for i := 0; i < len(raw); i += size {
+			prefixes = append(prefixes, string(raw[i:i+size]))
+		}
+	}
+	sort.Strings(prefixes)
+
+	if update.Checksum != nil && update.Checksum.SHA256 != "" {
+		want, err := base64.StdEncoding.DecodeString(update.Checksum.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum: %w", err)
+		}
+		got := sha256.Sum256([]byte(strings.Join(prefixes, "")))
+		if !bytes.Equal(got[:], want) {
+			return nil, fmt.Errorf("checksum mismatch")
+		}
+	}
+
+	return prefixes, nil
+}
+
+// Len returns the number of distinct prefixes in the list
+func (t *ThreatList) Len() int {
+	return t.count
+}
+
+// Match reports whether any Safe Browsing expression of the URL has a listed hash prefix
+func (t *ThreatList) Match(u *url.URL) bool {
+	if t == nil || t.count == 0 {
+		return false
+	}
+
+	for _, expr := range urlExpressions(u) {
+		sum := sha256.Sum256([]byte(expr))
+		for size, set := range t.prefixes {
+			if _, ok := set[string(sum[:size])]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// urlExpressions returns the host-suffix/path-prefix combinations Safe Browsing hashes for a URL
+func urlExpressions(u *url.URL) []string {
+	host, path, query := canonicalize(u)
+	if host == "" {
+		return nil
+	}
+
+	var expressions []string
+	for _, h := range hostSuffixes(host) {
+		for _, p := range pathPrefixes(path, query) {
+			expressions = append(expressions, h+p)
+		}
+	}
+	return expressions
+}
+
+// canonicalize applies the Safe Browsing URL canonicalization rules
+func canonicalize(u *url.URL) (host, path, query string) {
+	host = strings.ToLower(fullyUnescape(u.Hostname()))
+	host = strings.Trim(host, ".")
+	for strings.Contains(host, "..") {
+		host = strings.ReplaceAll(host, "..", ".")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+
+	path = fullyUnescape(u.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+	path = cleanPath(path)
+
+	if u.RawQuery != "" || u.ForceQuery {
+		query = "?" + u.RawQuery
+	}
+
+	return escapeExpression(host), escapeExpression(path), query
+}
+
+// fullyUnescape percent-unescapes a string until it stops changing
+func fullyUnescape(s string) string {
+	for i := 0; i < 10; i++ {
+		next, err := url.PathUnescape(s)
+		if err != nil || next == s {
+			return s
+		}
+		s = next
+	}
+	return s
+}
+
+// cleanPath resolves "." and ".." segments and collapses repeated slashes, keeping a trailing slash
+func cleanPath(path string) string {
+	trailing := strings.HasSuffix(path, "/")
+
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, seg)
+		}
+	}
+
+	cleaned := "/" + strings.Join(segments, "/")
+	if trailing && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// escapeExpression percent-escapes characters <= ASCII 32, >= 127, '#' and '%'
+func escapeExpression(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= 32 || c >= 127 || c == '#' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// hostSuffixes returns the exact host plus up to four suffixes built from the last five components
+func hostSuffixes(host string) []string {
+	suffixes := []string{host}
+	if net.ParseIP(host) != nil {
+		return suffixes
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 5 {
+		parts = parts[len(parts)-5:]
+	}
+	for i := 1; i < len(parts)-1 && len(suffixes) < 5; i++ {
+		suffix := strings.Join(parts[i:], ".")
+		if suffix != host {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// pathPrefixes returns the exact path with and without query plus up to four directory prefixes
+func pathPrefixes(path, query string) []string {
+	var prefixes []string
+	if query != "" {
+		prefixes = append(prefixes, path+query)
+	}
+	prefixes = append(prefixes, path)
+
+	if path == "/" {
+		return prefixes
+	}
+
+	candidates := []string{"/"}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	current := "/"
+	for _, seg := range segments[:len(segments)-1] {
+		if len(candidates) >= 4 {
+			break
+		}
+		current += seg + "/"
+		candidates = append(candidates, current)
+	}
+	for _, c := range candidates {
+		if c != path {
+			prefixes = append(prefixes, c)
+		}
+	}
+	return prefixes
+}
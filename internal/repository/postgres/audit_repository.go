@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// AuditRepository reads the audit log. Entries are written by the URL repository in the
+// same transaction as the change they record.
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// auditRow is an audit_log row with its snapshots still encoded as JSON
+type auditRow struct {
+	models.AuditEntry
+	BeforeJSON []byte `db:"before"`
+	AfterJSON  []byte `db:"after"`
+}
+
+// auditTx runs change on a link in one transaction with an audit entry recording the link
+// before and after it, and a new version if the change edited it. change gets the link as
+// it was, locked for the transaction. A missing link before the change is ErrURLNotFound;
+// one missing after it was purged.
+func auditTx(ctx context.Context, db *sqlx.DB, urlID int64, entry *models.AuditEntry, change func(tx *sqlx.Tx, before *models.URL) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotURL(ctx, tx, urlID, true)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return models.ErrURLNotFound
+	}
+
+	if err := change(tx, before); err != nil {
+		return err
+	}
+	if err := recordVersion(ctx, tx, urlID, entry.ActorID); err != nil {
+		return err
+	}
+
+	after, err := snapshotURL(ctx, tx, urlID, false)
+	if err != nil {
+		return err
+	}
+
+	entry.URLID, entry.Before, entry.After = urlID, before, after
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// snapshotURL reads a link inside a transaction, locking its row when forUpdate is set.
+// A missing link is returned as nil.
+func snapshotURL(ctx context.Context, tx *sqlx.Tx, id int64, forUpdate bool) (*models.URL, error) {
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	url := &models.URL{}
+	err := tx.GetContext(ctx, url, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return url, err
+}
+
+// insertAuditEntry appends an entry to the audit log
+func insertAuditEntry(ctx context.Context, tx *sqlx.Tx, entry *models.AuditEntry) error {
+	before, err := encodeSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := encodeSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (action, url_id, actor_type, actor_id, ip, request_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return tx.QueryRowContext(ctx, query,
+		entry.Action,
+		entry.URLID,
+		entry.ActorType,
+		entry.ActorID,
+		entry.IP,
+		entry.RequestID,
+		before,
+		after,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// encodeSnapshot encodes a link snapshot for a JSONB column; nil stays NULL
+func encodeSnapshot(url *models.URL) ([]byte, error) {
+	if url == nil {
+		return nil, nil
+	}
+	return json.Marshal(url)
+}
+
+// List returns a page of audit entries matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.URLID != 0 {
+		args = append(args, filter.URLID)
+		conditions = append(conditions, fmt.Sprintf("url_id = $%d", len(args)))
+	}
+	if filter.ActorID != 0 {
+		args = append(args, filter.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	if filter.ActorType != "" {
+		args = append(args, filter.ActorType)
+		conditions = append(conditions, fmt.Sprintf("actor_type = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if filter.RequestID != "" {
+		args = append(args, filter.RequestID)
+		conditions = append(conditions, fmt.Sprintf("request_id = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := `
+		SELECT id, action, url_id, actor_type, actor_id, ip, request_id, before, after, created_at
+		FROM audit_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	var rows []auditRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.AuditEntry, len(rows))
+	for i := range rows {
+		entry := rows[i].AuditEntry
+		if err := decodeSnapshot(rows[i].BeforeJSON, &entry.Before); err != nil {
+			return nil, err
+		}
+		if err := decodeSnapshot(rows[i].AfterJSON, &entry.After); err != nil {
+			return nil, err
+		}
+		entries[i] = &entry
+	}
+	return entries, nil
+}
+
+// decodeSnapshot decodes a JSONB link snapshot; NULL leaves it nil
+func decodeSnapshot(data []byte, url **models.URL) error {
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, url)
+}
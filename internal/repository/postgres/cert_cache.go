@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// CertCache stores ACME account keys and certificates in the database so every replica
+// serves the same certificates and only one of them has to issue each.
+type CertCache struct {
+	db *sqlx.DB
+}
+
+// NewCertCache creates a new CertCache
+func NewCertCache(db *sqlx.DB) *CertCache {
+	return &CertCache{db: db}
+}
+
+// Get returns the cached data for key, or autocert.ErrCacheMiss
+func (c *CertCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := c.db.GetContext(ctx, &data, `SELECT data FROM acme_cache WHERE key = $1`, key)
+	if err == sql.ErrNoRows {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put stores data under key, replacing any previous value
+func (c *CertCache) Put(ctx context.Context, key string, data []byte) error {
+	query := `
+		INSERT INTO acme_cache (key, data, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated_at = NOW()
+	`
+
+	_, err := c.db.ExecContext(ctx, query, key, data)
+	return err
+}
+
+// Delete removes key from the cache
+func (c *CertCache) Delete(ctx context.Context, key string) error {
+	_, err := c.db.ExecContext(ctx, `DELETE FROM acme_cache WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+const domainColumns = `id, host, scheme, active, owner_user_id, owner_workspace_id, verification_token, verified_at, created_at`
+
+// DomainRepository handles database operations for short domains
+type DomainRepository struct {
+	db *sqlx.DB
+}
+
+// NewDomainRepository creates a new DomainRepository
+func NewDomainRepository(db *sqlx.DB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+// ListDomains returns all registered domains
+func (r *DomainRepository) ListDomains(ctx context.Context) ([]*models.Domain, error) {
+	query := `
+		SELECT ` + domainColumns + `
+		FROM domains
+		ORDER BY host
+	`
+
+	domains := []*models.Domain{}
+	err := r.db.SelectContext(ctx, &domains, query)
+	return domains, err
+}
+
+// FindDomainByHost retrieves a domain by its host
+func (r *DomainRepository) FindDomainByHost(ctx context.Context, host string) (*models.Domain, error) {
+	query := `
+		SELECT ` + domainColumns + `
+		FROM domains
+		WHERE host = $1
+	`
+
+	domain := &models.Domain{}
+	err := r.db.GetContext(ctx, domain, query, host)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrDomainNotFound
+	}
+	return domain, err
+}
+
+// FindDomainByID retrieves a domain by its ID
+func (r *DomainRepository) FindDomainByID(ctx context.Context, id int64) (*models.Domain, error) {
+	query := `
+		SELECT ` + domainColumns + `
+		FROM domains
+		WHERE id = $1
+	`
+
+	domain := &models.Domain{}
+	err := r.db.GetContext(ctx, domain, query, id)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrDomainNotFound
+	}
+	return domain, err
+}
+
+// CreateDomain registers a domain that is trusted without verification
+func (r *DomainRepository) CreateDomain(ctx context.Context, domain *models.Domain) error {
+	query := `
+		INSERT INTO domains (host, scheme, active, owner_workspace_id, verified_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, verified_at, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, domain.Host, domain.Scheme, domain.Active, domain.OwnerWorkspaceID).Scan(&domain.ID, &domain.VerifiedAt, &domain.CreatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.ErrDuplicateDomain
+	}
+	return err
+}
+
+// RegisterPendingDomain stores a domain awaiting ownership verification. A pending claim on the
+// same host older than pendingTTL is taken over, along with its ownership, so abandoned
+// registrations don't block the owner.
+func (r *DomainRepository) RegisterPendingDomain(ctx context.Context, domain *models.Domain, pendingTTL time.Duration) error {
+	query := `
+		INSERT INTO domains (host, scheme, active, owner_user_id, owner_workspace_id, verification_token)
+		VALUES ($1, $2, FALSE, $3, $4, $5)
+		ON CONFLICT (host) DO UPDATE
+		SET scheme = EXCLUDED.scheme, owner_user_id = EXCLUDED.owner_user_id, owner_workspace_id = EXCLUDED.owner_workspace_id,
+			verification_token = EXCLUDED.verification_token, created_at = NOW()
+		WHERE domains.verified_at IS NULL AND domains.created_at < NOW() - make_interval(secs => $6)
+		RETURNING id, active, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, domain.Host, domain.Scheme, domain.OwnerUserID, domain.OwnerWorkspaceID, domain.VerificationToken, pendingTTL.Seconds()).
+		Scan(&domain.ID, &domain.Active, &domain.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.ErrDuplicateDomain
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.ErrDuplicateDomain
+	}
+	return err
+}
+
+// MarkDomainVerified records a domain's ownership as proven and opens it for new links. token is
+// the one that was checked: if the pending claim expired and was taken over with a new token in
+// the meantime, nothing is verified and ErrDomainNotFound is returned.
+func (r *DomainRepository) MarkDomainVerified(ctx context.Context, id int64, token string) error {
+	query := `
+		UPDATE domains SET verified_at = NOW(), active = TRUE
+		WHERE id = $1 AND verification_token = $2 AND verified_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, token)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrDomainNotFound
+	}
+	return nil
+}
+
+// SetDomainActive enables or disables new links on a domain
+func (r *DomainRepository) SetDomainActive(ctx context.Context, id int64, active bool) error {
+	query := `UPDATE domains SET active = $1 WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, active, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrDomainNotFound
+	}
+	return nil
+}
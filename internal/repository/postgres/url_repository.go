@@ -1,158 +1,825 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-	"time"
-
-	"github.com/jmoiron/sqlx"
-	"github.com/rakheshkrishna2005/url-shortener/internal/models"
-)
-
-// URLRepository handles database operations for URLs
-type URLRepository struct {
-	db *sqlx.DB
-}
-
-// NewURLRepository creates a new URLRepository
-func NewURLRepository(db *sqlx.DB) *URLRepository {
-	return &URLRepository{db: db}
-}
-
-// Store saves a URL to the database
-func (r *URLRepository) Store(ctx context.Context, url *models.URL) error {
-	query := `
-		INSERT INTO urls (original_url, short_code, custom_alias, expires_at, user_ip)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at
-	`
-
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		url.OriginalURL,
-		url.ShortCode,
-		url.CustomAlias,
-		url.ExpiresAt,
-		url.UserIP,
-	).Scan(&url.ID, &url.CreatedAt)
-
-	return err
-}
-
-// FindByShortCode retrieves a URL by its short code
-func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*models.URL, error) {
-	query := `
-		SELECT id, original_url, short_code, custom_alias, created_at, expires_at, user_ip
-		FROM urls
-		WHERE short_code = $1
-	`
-
-	url := &models.URL{}
-	err := r.db.GetContext(ctx, url, query, shortCode)
-	if err == sql.ErrNoRows {
-		return nil, models.ErrURLNotFound
-	}
-	return url, err
-}
-
-// FindByID retrieves a URL by its ID
-func (r *URLRepository) FindByID(ctx context.Context, id int64) (*models.URL, error) {
-	query := `
-		SELECT id, original_url, short_code, custom_alias, created_at, expires_at, user_ip
-		FROM urls
-		WHERE id = $1
-	`
-
-	url := &models.URL{}
-	err := r.db.GetContext(ctx, url, query, id)
-	if err == sql.ErrNoRows {
-		return nil, models.ErrURLNotFound
-	}
-	return url, err
-}
-
-// FindByCustomAlias checks if a custom alias already exists
-func (r *URLRepository) FindByCustomAlias(ctx context.Context, alias string) (*models.URL, error) {
-	query := `
-		SELECT id, original_url, short_code, custom_alias, created_at, expires_at, user_ip
-		FROM urls
-		WHERE custom_alias = $1
-	`
-
-	url := &models.URL{}
-	err := r.db.GetContext(ctx, url, query, alias)
-	if err == sql.ErrNoRows {
-		return nil, models.ErrURLNotFound
-	}
-	return url, err
-}
-
-// Update updates a URL record
-func (r *URLRepository) Update(ctx context.Context, url *models.URL) error {
-	query := `
-		UPDATE urls
-		SET original_url = $1, custom_alias = $2, expires_at = $3
-		WHERE id = $4
-	`
-
-	_, err := r.db.ExecContext(
-		ctx,
-		query,
-		url.OriginalURL,
-		url.CustomAlias,
-		url.ExpiresAt,
-		url.ID,
-	)
-	return err
-}
-
-// Delete removes a URL record by ID
-func (r *URLRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM urls WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
-}
-
-// RecordClick adds a click event for analytics
-func (r *URLRepository) RecordClick(ctx context.Context, event *models.ClickEvent) error {
-	query := `
-		INSERT INTO analytics (url_id, referer, user_agent, ip_address)
-		VALUES ($1, $2, $3, $4)
-	`
-
-	_, err := r.db.ExecContext(
-		ctx,
-		query,
-		event.URLID,
-		event.Referer,
-		event.UserAgent,
-		event.IPAddress,
-	)
-	return err
-}
-
-// GetURLStats retrieves analytics for a URL
-func (r *URLRepository) GetURLStats(ctx context.Context, urlID int64) (*models.URLStats, error) {
-	query := `
-		SELECT COUNT(*) as click_count, MAX(accessed_at) as last_click
-		FROM analytics
-		WHERE url_id = $1
-	`
-
-	var stats models.URLStats
-	var lastClick sql.NullTime
-
-	err := r.db.QueryRowContext(ctx, query, urlID).Scan(&stats.ClickCount, &lastClick)
-	if err != nil {
-		return nil, err
-	}
-
-	if lastClick.Valid {
-		stats.LastClick = lastClick.Time
-	} else {
-		stats.LastClick = time.Time{}
-	}
-
-	return &stats, nil
-}
\ No newline at end of file
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+// primaryAlias selects a link's primary alias: its oldest active one
+const primaryAlias = `(SELECT url_aliases.alias FROM url_aliases
+		WHERE url_aliases.url_id = urls.id AND url_aliases.retired_at IS NULL
+		ORDER BY url_aliases.created_at, url_aliases.alias LIMIT 1)`
+
+// urlColumns lists the columns scanned into models.URL. custom_alias is the link's primary alias.
+const urlColumns = `id, original_url, short_code, domain_id, created_at, expires_at, user_ip, user_id,
+	workspace_id, always_preview, status, status_reason, status_changed_at, deleted_at, version,
+	` + primaryAlias + ` AS custom_alias`
+
+// sameDomain compares a domain_id column with a parameter the way the per-domain unique indexes do,
+// with NULL standing for the default domain
+func sameDomain(column, param string) string {
+	return "COALESCE(" + column + ", 0) = COALESCE(" + param + "::integer, 0)"
+}
+
+// aliasResolves matches url_aliases rows that still redirect: active ones and renamed ones within their grace period
+const aliasResolves = `(url_aliases.retired_at IS NULL OR url_aliases.redirect_until > NOW())`
+
+// URLRepository handles database operations for URLs
+type URLRepository struct {
+	db       *sqlx.DB
+	alphabet *utils.Alphabet
+}
+
+// NewURLRepository creates a new URLRepository. Short codes and aliases are looked up as typed
+// and in the alphabet's normalized form, preferring an exact match.
+func NewURLRepository(db *sqlx.DB, alphabet *utils.Alphabet) *URLRepository {
+	return &URLRepository{
+		db:       db,
+		alphabet: alphabet,
+	}
+}
+
+// Store saves a URL to the database together with its custom alias, if any. Links with an
+// owner are counted against the owner's quota limits, and the creation is recorded in the
+// audit log and as the link's first version, in the same transaction.
+func (r *URLRepository) Store(ctx context.Context, url *models.URL, limits models.QuotaLimits, audit *models.AuditEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if owner, ok := url.QuotaOwner(); ok {
+		if err := reserveLink(ctx, tx, owner, limits); err != nil {
+			return err
+		}
+	}
+
+	// Generated codes share their namespace with aliases
+	var taken bool
+	err = tx.GetContext(ctx, &taken, `
+		SELECT EXISTS (
+			SELECT 1 FROM url_aliases
+			WHERE alias = $1 AND `+sameDomain("url_aliases.domain_id", "$2")+` AND `+aliasResolves+`
+		)
+	`, url.ShortCode, url.DomainID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return models.ErrDuplicateShortCode
+	}
+
+	query := `
+		INSERT INTO urls (original_url, short_code, domain_id, expires_at, user_ip, user_id, workspace_id, always_preview, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	if url.Status == "" {
+		url.Status = models.StatusActive
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		url.OriginalURL,
+		url.ShortCode,
+		url.DomainID,
+		url.ExpiresAt,
+		url.UserIP,
+		url.UserID,
+		url.WorkspaceID,
+		url.AlwaysPreview,
+		url.Status,
+	).Scan(&url.ID, &url.CreatedAt)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	if url.CustomAlias != nil {
+		if err := addAlias(ctx, tx, url.ID, *url.CustomAlias); err != nil {
+			return err
+		}
+	}
+
+	if err := recordVersion(ctx, tx, url.ID, audit.ActorID); err != nil {
+		return err
+	}
+	after, err := snapshotURL(ctx, tx, url.ID, false)
+	if err != nil {
+		return err
+	}
+	audit.URLID, audit.After = url.ID, after
+	if err := insertAuditEntry(ctx, tx, audit); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// mapUniqueViolation turns unique constraint violations on urls and url_aliases into model errors
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+
+	switch pqErr.Constraint {
+	case "urls_domain_short_code_key":
+		return models.ErrDuplicateShortCode
+	case "url_aliases_domain_alias_key":
+		return models.ErrDuplicateAlias
+	}
+	return err
+}
+
+// ReserveCodeIDs reserves n consecutive short code counter values and returns the first one
+func (r *URLRepository) ReserveCodeIDs(ctx context.Context, n int64) (int64, error) {
+	query := `
+		UPDATE short_code_counter
+		SET next_value = next_value + $1
+		RETURNING next_value - $1
+	`
+
+	var start int64
+	err := r.db.QueryRowContext(ctx, query, n).Scan(&start)
+	return start, err
+}
+
+// CountShortCodesByLength returns how many short codes of each length are taken on the domain
+// with the most of that length, including those in the trash. Codes are unique per domain, so
+// the busiest domain is the one whose keyspace fills up first.
+func (r *URLRepository) CountShortCodesByLength(ctx context.Context) (map[int]int64, error) {
+	query := `
+		SELECT length, MAX(count) AS count
+		FROM (
+			SELECT length(short_code) AS length, COUNT(*) AS count
+			FROM urls
+			GROUP BY domain_id, length(short_code)
+		) per_domain
+		GROUP BY length
+	`
+
+	var rows []struct {
+		Length int   `db:"length"`
+		Count  int64 `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Length] = row.Count
+	}
+	return counts, nil
+}
+
+// FindByShortCode retrieves a URL by its short code on a domain; a nil domain is the default domain
+func (r *URLRepository) FindByShortCode(ctx context.Context, domainID *int64, shortCode string) (*models.URL, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE short_code = ANY($1) AND ` + sameDomain("domain_id", "$2") + ` AND deleted_at IS NULL
+		ORDER BY array_position($1::text[], short_code::text)
+		LIMIT 1
+	`
+
+	url := &models.URL{}
+	err := r.db.GetContext(ctx, url, query, pq.Array(r.alphabet.Variants(shortCode)), domainID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return url, err
+}
+
+// FindByID retrieves a URL by its ID
+func (r *URLRepository) FindByID(ctx context.Context, id int64) (*models.URL, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	url := &models.URL{}
+	err := r.db.GetContext(ctx, url, query, id)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return url, err
+}
+
+// FindDeleted retrieves a URL in the trash by its ID
+func (r *URLRepository) FindDeleted(ctx context.Context, id int64) (*models.URL, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	url := &models.URL{}
+	err := r.db.GetContext(ctx, url, query, id)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return url, err
+}
+
+// CodeTaken reports whether a short code or alias is in use on a domain. Codes of links in
+// the trash and renamed aliases still within their grace period count as taken.
+func (r *URLRepository) CodeTaken(ctx context.Context, domainID *int64, code string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM urls
+			WHERE short_code = ANY($1) AND ` + sameDomain("domain_id", "$2") + `
+		) OR EXISTS (
+			SELECT 1 FROM url_aliases
+			WHERE alias = ANY($1) AND ` + sameDomain("url_aliases.domain_id", "$2") + ` AND ` + aliasResolves + `
+		)
+	`
+
+	var taken bool
+	err := r.db.GetContext(ctx, &taken, query, pq.Array(r.alphabet.Variants(code)), domainID)
+	return taken, err
+}
+
+// FindByAlias retrieves the URL an alias on a domain points to, including renamed aliases within their grace period
+func (r *URLRepository) FindByAlias(ctx context.Context, domainID *int64, alias string) (*models.URL, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE id = (
+			SELECT url_id FROM url_aliases
+			WHERE alias = ANY($1) AND ` + sameDomain("url_aliases.domain_id", "$2") + ` AND ` + aliasResolves + `
+			ORDER BY array_position($1::text[], alias::text)
+			LIMIT 1
+		) AND deleted_at IS NULL
+	`
+
+	url := &models.URL{}
+	err := r.db.GetContext(ctx, url, query, pq.Array(r.alphabet.Variants(alias)), domainID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return url, err
+}
+
+// ListAliases returns a URL's aliases, including renamed ones still within their grace period
+func (r *URLRepository) ListAliases(ctx context.Context, urlID int64) ([]*models.URLAlias, error) {
+	query := `
+		SELECT alias, url_id, created_at, retired_at, redirect_until
+		FROM url_aliases
+		WHERE url_id = $1 AND ` + aliasResolves + `
+		ORDER BY retired_at NULLS FIRST, created_at, alias
+	`
+
+	aliases := []*models.URLAlias{}
+	err := r.db.SelectContext(ctx, &aliases, query, urlID)
+	return aliases, err
+}
+
+// AddAlias gives a URL another alias
+func (r *URLRepository) AddAlias(ctx context.Context, urlID int64, alias string, audit *models.AuditEntry) error {
+	return auditTx(ctx, r.db, urlID, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		return addAlias(ctx, tx, urlID, alias)
+	})
+}
+
+// RenameAlias replaces an active alias with a new one. The old alias keeps
+// redirecting until redirectUntil; a zero time retires it immediately.
+func (r *URLRepository) RenameAlias(ctx context.Context, urlID int64, oldAlias, newAlias string, redirectUntil time.Time, audit *models.AuditEntry) error {
+	return auditTx(ctx, r.db, urlID, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		if err := retireAlias(ctx, tx, urlID, r.alphabet.Variants(oldAlias), redirectUntil); err != nil {
+			return err
+		}
+		return addAlias(ctx, tx, urlID, newAlias)
+	})
+}
+
+// RemoveAlias stops an active alias from resolving right away
+func (r *URLRepository) RemoveAlias(ctx context.Context, urlID int64, alias string, audit *models.AuditEntry) error {
+	return auditTx(ctx, r.db, urlID, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		return retireAlias(ctx, tx, urlID, r.alphabet.Variants(alias), time.Time{})
+	})
+}
+
+// addAlias inserts an alias on the URL's domain unless it's a short code there or still resolves.
+// Aliases whose grace period has passed are taken over.
+func addAlias(ctx context.Context, db sqlx.ExecerContext, urlID int64, alias string) error {
+	query := `
+		INSERT INTO url_aliases (alias, url_id, domain_id)
+		SELECT $1::varchar, urls.id, urls.domain_id
+		FROM urls
+		WHERE urls.id = $2::integer AND NOT EXISTS (
+			SELECT 1 FROM urls other
+			WHERE other.short_code = $1 AND ` + sameDomain("other.domain_id", "urls.domain_id") + `
+		)
+		ON CONFLICT ((COALESCE(domain_id, 0)), alias) DO UPDATE
+		SET url_id = EXCLUDED.url_id, created_at = NOW(), retired_at = NULL, redirect_until = NULL
+		WHERE url_aliases.redirect_until <= NOW()
+	`
+
+	result, err := db.ExecContext(ctx, query, alias, urlID)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrDuplicateAlias
+	}
+	return nil
+}
+
+// retireAlias marks an active alias as renamed, keeping it resolvable until redirectUntil.
+// The first of the variants that is one of the URL's active aliases is retired.
+func retireAlias(ctx context.Context, db sqlx.ExecerContext, urlID int64, variants []string, redirectUntil time.Time) error {
+	query := `
+		UPDATE url_aliases
+		SET retired_at = NOW(), redirect_until = GREATEST($3, NOW())
+		WHERE url_id = $1 AND retired_at IS NULL AND alias = (
+			SELECT alias FROM url_aliases
+			WHERE url_id = $1 AND alias = ANY($2) AND retired_at IS NULL
+			ORDER BY array_position($2::text[], alias::text)
+			LIMIT 1
+		)
+	`
+
+	result, err := db.ExecContext(ctx, query, urlID, pq.Array(variants), redirectUntil)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrAliasNotFound
+	}
+	return nil
+}
+
+// Update writes a URL's destination, expiry, preview setting and primary alias in one
+// transaction, then fills url in as saved. A replaced primary alias keeps redirecting until
+// aliasRedirectUntil. A non-zero ifVersion makes the update apply only to that version of
+// the URL, returning ErrVersionMismatch otherwise. Moving an expired link's expiry out of
+// the way makes it active again, so that is checked against limits like Undelete.
+func (r *URLRepository) Update(ctx context.Context, url *models.URL, ifVersion int, aliasRedirectUntil time.Time, limits models.QuotaLimits, audit *models.AuditEntry) error {
+	query := `
+		UPDATE urls
+		SET original_url = $1, expires_at = $2, always_preview = $3
+		WHERE id = $4
+	`
+
+	err := auditTx(ctx, r.db, url.ID, audit, func(tx *sqlx.Tx, before *models.URL) error {
+		if before.DeletedAt != nil {
+			return models.ErrURLNotFound
+		}
+		if ifVersion != 0 && before.Version != ifVersion {
+			return models.ErrVersionMismatch
+		}
+		now := time.Now()
+		if before.Status == models.StatusActive && before.ExpiredAt(now) && !url.ExpiredAt(now) {
+			if owner, ok := before.QuotaOwner(); ok {
+				if err := checkActiveLinks(ctx, tx, owner, limits); err != nil {
+					return err
+				}
+			}
+		}
+		if err := setPrimaryAlias(ctx, tx, url.ID, before.CustomAlias, url.CustomAlias, aliasRedirectUntil); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			url.OriginalURL,
+			url.ExpiresAt,
+			url.AlwaysPreview,
+			url.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	*url = *audit.After
+	return nil
+}
+
+// setPrimaryAlias makes alias the URL's primary alias in place of current. A renamed alias
+// keeps redirecting until redirectUntil; a nil alias removes the current one right away.
+func setPrimaryAlias(ctx context.Context, tx *sqlx.Tx, urlID int64, current, alias *string, redirectUntil time.Time) error {
+	switch {
+	case alias == nil && current == nil:
+		return nil
+	case alias != nil && current != nil && *alias == *current:
+		return nil
+	case alias == nil:
+		return retireAlias(ctx, tx, urlID, []string{*current}, time.Time{})
+	case current == nil:
+		return addAlias(ctx, tx, urlID, *alias)
+	}
+
+	if err := retireAlias(ctx, tx, urlID, []string{*current}, redirectUntil); err != nil {
+		return err
+	}
+	return addAlias(ctx, tx, urlID, *alias)
+}
+
+// UpdateStatus changes the moderation status of a URL, keeping the row and its analytics
+func (r *URLRepository) UpdateStatus(ctx context.Context, id int64, status models.URLStatus, reason *string, audit *models.AuditEntry) error {
+	query := `
+		UPDATE urls
+		SET status = $1, status_reason = $2, status_changed_at = NOW()
+		WHERE id = $3
+	`
+
+	return auditTx(ctx, r.db, id, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		_, err := tx.ExecContext(ctx, query, status, reason, id)
+		return err
+	})
+}
+
+// Delete moves a URL to the trash. Its row, aliases and analytics are kept until it's purged.
+func (r *URLRepository) Delete(ctx context.Context, id int64, audit *models.AuditEntry) error {
+	query := `UPDATE urls SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	return auditTx(ctx, r.db, id, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// Undelete takes a URL out of the trash. A link with an owner counts against the owner's
+// active link limit again, checked under the same lock as link creation.
+func (r *URLRepository) Undelete(ctx context.Context, url *models.URL, limits models.QuotaLimits, audit *models.AuditEntry) error {
+	query := `UPDATE urls SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	return auditTx(ctx, r.db, url.ID, audit, func(tx *sqlx.Tx, _ *models.URL) error {
+		if owner, ok := url.QuotaOwner(); ok {
+			if err := checkActiveLinks(ctx, tx, owner, limits); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, query, url.ID)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return models.ErrURLNotFound
+		}
+		return nil
+	})
+}
+
+// PurgeDeleted permanently deletes up to limit URLs that went to the trash before cutoff,
+// together with their aliases and analytics, and returns how many it purged. Each purge is
+// recorded in the audit log with the link as it was.
+func (r *URLRepository) PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Skip links someone is restoring right now
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	urls := []*models.URL{}
+	if err := tx.SelectContext(ctx, &urls, query, cutoff, limit); err != nil {
+		return 0, err
+	}
+
+	for _, url := range urls {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM urls WHERE id = $1`, url.ID); err != nil {
+			return 0, err
+		}
+		entry := &models.AuditEntry{
+			Action:    models.AuditURLPurged,
+			URLID:     url.ID,
+			ActorType: models.AuditActorSystem,
+			Before:    url,
+		}
+		if err := insertAuditEntry(ctx, tx, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(urls), tx.Commit()
+}
+
+// RecordClick adds a click event for analytics
+func (r *URLRepository) RecordClick(ctx context.Context, event *models.ClickEvent) error {
+	query := `
+		INSERT INTO analytics (url_id, referer, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		event.URLID,
+		event.Referer,
+		event.UserAgent,
+		event.IPAddress,
+	)
+	return err
+}
+
+// GetURLStats retrieves analytics for a URL
+func (r *URLRepository) GetURLStats(ctx context.Context, urlID int64) (*models.URLStats, error) {
+	query := `
+		SELECT COUNT(*) as click_count, MAX(accessed_at) as last_click
+		FROM analytics
+		WHERE url_id = $1
+	`
+
+	var stats models.URLStats
+	var lastClick sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, query, urlID).Scan(&stats.ClickCount, &lastClick)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastClick.Valid {
+		stats.LastClick = lastClick.Time
+	} else {
+		stats.LastClick = time.Time{}
+	}
+
+	return &stats, nil
+}
+
+// GetStatsForURLs retrieves analytics for several URLs, keyed by URL ID, with daily
+// click counts since the given time. URLs without clicks get zero stats.
+func (r *URLRepository) GetStatsForURLs(ctx context.Context, urlIDs []int64, since time.Time) (map[int64]*models.URLStats, error) {
+	results := make(map[int64]*models.URLStats, len(urlIDs))
+	if len(urlIDs) == 0 {
+		return results, nil
+	}
+	for _, id := range urlIDs {
+		results[id] = &models.URLStats{}
+	}
+
+	totalsQuery := `
+		SELECT url_id, COUNT(*) AS click_count, MAX(accessed_at) AS last_click
+		FROM analytics
+		WHERE url_id = ANY($1)
+		GROUP BY url_id
+	`
+
+	var totals []struct {
+		URLID      int64     `db:"url_id"`
+		ClickCount int       `db:"click_count"`
+		LastClick  time.Time `db:"last_click"`
+	}
+	if err := r.db.SelectContext(ctx, &totals, totalsQuery, pq.Array(urlIDs)); err != nil {
+		return nil, err
+	}
+	for _, t := range totals {
+		results[t.URLID].ClickCount = t.ClickCount
+		results[t.URLID].LastClick = t.LastClick
+	}
+
+	dailyQuery := `
+		SELECT url_id, date_trunc('day', accessed_at AT TIME ZONE 'UTC') AS day, COUNT(*) AS clicks
+		FROM analytics
+		WHERE url_id = ANY($1) AND accessed_at >= $2
+		GROUP BY url_id, day
+		ORDER BY day
+	`
+
+	var daily []struct {
+		URLID int64 `db:"url_id"`
+		models.DailyClicks
+	}
+	if err := r.db.SelectContext(ctx, &daily, dailyQuery, pq.Array(urlIDs), since); err != nil {
+		return nil, err
+	}
+	for _, d := range daily {
+		results[d.URLID].Daily = append(results[d.URLID].Daily, d.DailyClicks)
+	}
+	return results, nil
+}
+
+// SaveMetadata stores the metadata fetched for a URL, replacing any earlier fetch.
+// It is a no-op when the URL's destination changed while the fetch was running.
+func (r *URLRepository) SaveMetadata(ctx context.Context, meta *models.URLMetadata) error {
+	query := `
+		INSERT INTO url_metadata (url_id, source_url, title, description, og_title, og_description,
+			og_site_name, og_image_url, favicon_url, status_code, fetch_error, fetched_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		WHERE EXISTS (SELECT 1 FROM urls WHERE id = $1 AND original_url = $2)
+		ON CONFLICT (url_id) DO UPDATE SET
+			source_url = EXCLUDED.source_url,
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			og_title = EXCLUDED.og_title,
+			og_description = EXCLUDED.og_description,
+			og_site_name = EXCLUDED.og_site_name,
+			og_image_url = EXCLUDED.og_image_url,
+			favicon_url = EXCLUDED.favicon_url,
+			status_code = EXCLUDED.status_code,
+			fetch_error = EXCLUDED.fetch_error,
+			fetched_at = EXCLUDED.fetched_at
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		meta.URLID,
+		meta.SourceURL,
+		meta.Title,
+		meta.Description,
+		meta.OGTitle,
+		meta.OGDescription,
+		meta.OGSiteName,
+		meta.OGImageURL,
+		meta.FaviconURL,
+		meta.StatusCode,
+		meta.FetchError,
+		meta.FetchedAt,
+	)
+	return err
+}
+
+// GetMetadata retrieves the metadata fetched for a URL
+func (r *URLRepository) GetMetadata(ctx context.Context, urlID int64) (*models.URLMetadata, error) {
+	query := `
+		SELECT url_id, source_url, title, description, og_title, og_description,
+			og_site_name, og_image_url, favicon_url, status_code, fetch_error, fetched_at
+		FROM url_metadata
+		WHERE url_id = $1
+	`
+
+	meta := &models.URLMetadata{}
+	err := r.db.GetContext(ctx, meta, query, urlID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return meta, err
+}
+
+// List retrieves a page of URLs matching the filter, newest first
+func (r *URLRepository) List(ctx context.Context, filter models.URLFilter) ([]*models.URL, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.UserIP != "" {
+		args = append(args, filter.UserIP)
+		conditions = append(conditions, fmt.Sprintf("user_ip = $%d AND user_id IS NULL", len(args)))
+	}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d AND workspace_id IS NULL", len(args)))
+	}
+	if filter.WorkspaceID != 0 {
+		args = append(args, filter.WorkspaceID)
+		conditions = append(conditions, fmt.Sprintf("workspace_id = $%d", len(args)))
+	}
+	order := "created_at DESC, id DESC"
+	if filter.Deleted {
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+		order = "deleted_at DESC, id DESC"
+	} else {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	switch filter.Health {
+	case models.HealthBroken:
+		args = append(args, filter.MinFailures)
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT url_id FROM url_health WHERE consecutive_failures >= $%d)", len(args)))
+	case models.HealthHealthy:
+		args = append(args, filter.MinFailures)
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT url_id FROM url_health WHERE consecutive_failures < $%d)", len(args)))
+	}
+
+	query := `SELECT ` + urlColumns + ` FROM urls WHERE ` + strings.Join(conditions, " AND ")
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)-1, len(args))
+
+	urls := []*models.URL{}
+	err := r.db.SelectContext(ctx, &urls, query, args...)
+	return urls, err
+}
+
+// ListURLsDueForCheck retrieves active, unexpired URLs whose last health check is older than checkedBefore
+func (r *URLRepository) ListURLsDueForCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*models.URL, error) {
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		LEFT JOIN url_health ON url_health.url_id = urls.id
+		WHERE status = 'active' AND deleted_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (last_checked_at IS NULL OR last_checked_at < $1)
+		ORDER BY last_checked_at NULLS FIRST
+		LIMIT $2
+	`
+
+	urls := []*models.URL{}
+	err := r.db.SelectContext(ctx, &urls, query, checkedBefore, limit)
+	return urls, err
+}
+
+// RecordHealthCheck stores a health check result and fills in the updated consecutive failure count
+func (r *URLRepository) RecordHealthCheck(ctx context.Context, health *models.URLHealth, failed bool) error {
+	query := `
+		INSERT INTO url_health (url_id, status_code, final_url, latency_ms, consecutive_failures,
+			last_error, last_checked_at, last_success_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN 1 ELSE 0 END, $6, $7,
+			CASE WHEN $5 THEN NULL ELSE $7 END)
+		ON CONFLICT (url_id) DO UPDATE SET
+			status_code = EXCLUDED.status_code,
+			final_url = EXCLUDED.final_url,
+			latency_ms = EXCLUDED.latency_ms,
+			consecutive_failures = CASE WHEN $5 THEN url_health.consecutive_failures + 1 ELSE 0 END,
+			last_error = EXCLUDED.last_error,
+			last_checked_at = EXCLUDED.last_checked_at,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, url_health.last_success_at)
+		RETURNING consecutive_failures, last_success_at
+	`
+
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		health.URLID,
+		health.StatusCode,
+		health.FinalURL,
+		health.LatencyMS,
+		failed,
+		health.LastError,
+		health.LastCheckedAt,
+	).Scan(&health.ConsecutiveFailures, &health.LastSuccessAt)
+}
+
+// GetHealth retrieves the latest health check result for a URL
+func (r *URLRepository) GetHealth(ctx context.Context, urlID int64) (*models.URLHealth, error) {
+	query := `
+		SELECT url_id, status_code, final_url, latency_ms, consecutive_failures,
+			last_error, last_checked_at, last_success_at
+		FROM url_health
+		WHERE url_id = $1
+	`
+
+	health := &models.URLHealth{}
+	err := r.db.GetContext(ctx, health, query, urlID)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrURLNotFound
+	}
+	return health, err
+}
+
+// GetHealthForURLs retrieves the latest health check results for several URLs, keyed by URL ID
+func (r *URLRepository) GetHealthForURLs(ctx context.Context, urlIDs []int64) (map[int64]*models.URLHealth, error) {
+	results := make(map[int64]*models.URLHealth, len(urlIDs))
+	if len(urlIDs) == 0 {
+		return results, nil
+	}
+
+	query := `
+		SELECT url_id, status_code, final_url, latency_ms, consecutive_failures,
+			last_error, last_checked_at, last_success_at
+		FROM url_health
+		WHERE url_id = ANY($1)
+	`
+
+	var rows []*models.URLHealth
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(urlIDs)); err != nil {
+		return nil, err
+	}
+	for _, h := range rows {
+		results[h.URLID] = h
+	}
+	return results, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// currentPeriod is the usage period counted now: the first day of the month in UTC
+const currentPeriod = `(date_trunc('month', NOW() AT TIME ZONE 'UTC'))::date`
+
+// UsageRepository handles database operations for usage counters and workspace quotas
+type UsageRepository struct {
+	db *sqlx.DB
+}
+
+// NewUsageRepository creates a new UsageRepository
+func NewUsageRepository(db *sqlx.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// ownedLinks matches the links counted against an owner, with the owner's ID as $1
+func ownedLinks(owner models.QuotaOwner) string {
+	if owner.Kind == models.OwnerWorkspace {
+		return "workspace_id = $1"
+	}
+	return "user_id = $1 AND workspace_id IS NULL"
+}
+
+// activeLinks matches links that still redirect; links in the trash don't
+const activeLinks = `status = 'active' AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// quotaLockClass keeps each owner kind's advisory locks apart
+func quotaLockClass(owner models.QuotaOwner) int {
+	if owner.Kind == models.OwnerWorkspace {
+		return 1
+	}
+	return 2
+}
+
+// reserveLink counts a new link against its owner's quotas inside the transaction that stores it,
+// so a link that fails to store gives its slot back. An advisory lock per owner serializes link
+// creation, so concurrent requests can't both take the last active link.
+func reserveLink(ctx context.Context, tx *sqlx.Tx, owner models.QuotaOwner, limits models.QuotaLimits) error {
+	if err := checkActiveLinks(ctx, tx, owner, limits); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO usage_counters (owner_type, owner_id, period, links_created)
+		VALUES ($1, $2, `+currentPeriod+`, 1)
+		ON CONFLICT (owner_type, owner_id, period) DO UPDATE
+		SET links_created = usage_counters.links_created + 1
+		WHERE $3::bigint = 0 OR usage_counters.links_created < $3::bigint
+	`, owner.Kind, owner.ID, limits.LinksPerMonth)
+	if err != nil {
+		return err
+	}
+	return checkCounted(result, models.ErrLinkQuotaExceeded)
+}
+
+// checkActiveLinks takes the owner's quota lock and returns ErrActiveLinkQuotaExceeded if
+// the owner has no active links to spare. The lock is held until the transaction ends.
+func checkActiveLinks(ctx context.Context, tx *sqlx.Tx, owner models.QuotaOwner, limits models.QuotaLimits) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, quotaLockClass(owner), owner.ID); err != nil {
+		return err
+	}
+
+	if limits.ActiveLinks > 0 {
+		var active int64
+		err := tx.GetContext(ctx, &active, `SELECT COUNT(*) FROM urls WHERE `+ownedLinks(owner)+` AND `+activeLinks, owner.ID)
+		if err != nil {
+			return err
+		}
+		if active >= limits.ActiveLinks {
+			return models.ErrActiveLinkQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// CountClick adds a click to the owner's monthly count unless that would go over limit.
+// The conditional upsert is a single statement, so concurrent clicks never overshoot.
+func (r *UsageRepository) CountClick(ctx context.Context, owner models.QuotaOwner, limit int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO usage_counters (owner_type, owner_id, period, clicks)
+		VALUES ($1, $2, `+currentPeriod+`, 1)
+		ON CONFLICT (owner_type, owner_id, period) DO UPDATE
+		SET clicks = usage_counters.clicks + 1
+		WHERE $3::bigint = 0 OR usage_counters.clicks < $3::bigint
+	`, owner.Kind, owner.ID, limit)
+	if err != nil {
+		return err
+	}
+	return checkCounted(result, models.ErrClickQuotaExceeded)
+}
+
+// checkCounted returns exceeded when a conditional counter upsert changed nothing
+func checkCounted(result sql.Result, exceeded error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return exceeded
+	}
+	return nil
+}
+
+// GetUsage returns the owner's counts for the current period. Limits are left for the caller to fill in.
+func (r *UsageRepository) GetUsage(ctx context.Context, owner models.QuotaOwner) (*models.Usage, error) {
+	query := fmt.Sprintf(`
+		SELECT %s AS period,
+			COALESCE(usage_counters.links_created, 0) AS links_created,
+			COALESCE(usage_counters.clicks, 0) AS clicks,
+			(SELECT COUNT(*) FROM urls WHERE %s AND %s) AS active_links
+		FROM (SELECT 1) AS one
+		LEFT JOIN usage_counters ON usage_counters.owner_type = $2
+			AND usage_counters.owner_id = $1 AND usage_counters.period = %s
+	`, currentPeriod, ownedLinks(owner), activeLinks, currentPeriod)
+
+	usage := &models.Usage{}
+	if err := r.db.GetContext(ctx, usage, query, owner.ID, owner.Kind); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetQuotaOverrides returns a workspace's own limits; a workspace without any has all fields nil
+func (r *UsageRepository) GetQuotaOverrides(ctx context.Context, workspaceID int64) (*models.QuotaOverrides, error) {
+	overrides := &models.QuotaOverrides{}
+	err := r.db.GetContext(ctx, overrides, `
+		SELECT links_per_month, active_links, clicks_per_month
+		FROM workspace_quotas
+		WHERE workspace_id = $1
+	`, workspaceID)
+	if err == sql.ErrNoRows {
+		return overrides, nil
+	}
+	return overrides, err
+}
+
+// SetQuotaOverrides replaces a workspace's own limits
+func (r *UsageRepository) SetQuotaOverrides(ctx context.Context, workspaceID int64, overrides *models.QuotaOverrides) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO workspace_quotas (workspace_id, links_per_month, active_links, clicks_per_month)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id) DO UPDATE
+		SET links_per_month = EXCLUDED.links_per_month,
+			active_links = EXCLUDED.active_links,
+			clicks_per_month = EXCLUDED.clicks_per_month
+	`, workspaceID, overrides.LinksPerMonth, overrides.ActiveLinks, overrides.ClicksPerMonth)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return models.ErrWorkspaceNotFound
+	}
+	return err
+}
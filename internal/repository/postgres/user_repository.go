@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+const userColumns = `id, email, COALESCE(password_hash, '') AS password_hash, role, oidc_issuer, oidc_subject, created_at`
+
+// UserRepository handles database operations for users and their sessions
+type UserRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(db *sqlx.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// CreateUser stores a new user. Emails are unique regardless of case.
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO users (email, password_hash, role, oidc_issuer, oidc_subject)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	err := r.db.QueryRowContext(ctx, query, user.Email, user.PasswordHash, user.Role, user.OIDCIssuer, user.OIDCSubject).
+		Scan(&user.ID, &user.CreatedAt)
+	return mapUserUniqueViolation(err)
+}
+
+// mapUserUniqueViolation turns a clash on the email index into ErrDuplicateEmail
+func mapUserUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_email_key" {
+		return models.ErrDuplicateEmail
+	}
+	return err
+}
+
+// FindUserByIdentity retrieves the user linked to an identity provider's issuer and subject
+func (r *UserRepository) FindUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE oidc_issuer = $1 AND oidc_subject = $2
+	`
+
+	user := &models.User{}
+	err := r.db.GetContext(ctx, user, query, issuer, subject)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrUserNotFound
+	}
+	return user, err
+}
+
+// LinkIdentity attaches an identity provider's issuer and subject to a user that has none yet
+func (r *UserRepository) LinkIdentity(ctx context.Context, id int64, issuer, subject string) error {
+	query := `
+		UPDATE users
+		SET oidc_issuer = $2, oidc_subject = $3
+		WHERE id = $1 AND oidc_subject IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, issuer, subject)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrIdentityConflict
+	}
+	return nil
+}
+
+// UpdateUserProfile stores the email and role an identity provider last asserted for a user
+func (r *UserRepository) UpdateUserProfile(ctx context.Context, id int64, email string, role models.Role) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET email = $2, role = $3 WHERE id = $1`, id, email, role)
+	return mapUserUniqueViolation(err)
+}
+
+// FindUserByEmail retrieves a user by email, ignoring case
+func (r *UserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE LOWER(email) = LOWER($1)
+	`
+
+	user := &models.User{}
+	err := r.db.GetContext(ctx, user, query, email)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrUserNotFound
+	}
+	return user, err
+}
+
+// FindUserByID retrieves a user by ID
+func (r *UserRepository) FindUserByID(ctx context.Context, id int64) (*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1
+	`
+
+	user := &models.User{}
+	err := r.db.GetContext(ctx, user, query, id)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrUserNotFound
+	}
+	return user, err
+}
+
+// CreateSession stores a new session
+func (r *UserRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (token_hash, user_id, csrf_token, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	return r.db.QueryRowContext(ctx, query, session.TokenHash, session.UserID, session.CSRFToken, session.ExpiresAt).
+		Scan(&session.CreatedAt)
+}
+
+// FindSession retrieves an unexpired session by the hash of its token
+func (r *UserRepository) FindSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	query := `
+		SELECT token_hash, user_id, csrf_token, created_at, expires_at
+		FROM sessions
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	session := &models.Session{}
+	err := r.db.GetContext(ctx, session, query, tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrSessionNotFound
+	}
+	return session, err
+}
+
+// DeleteSession removes a session; removing one that doesn't exist is not an error
+func (r *UserRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash)
+	return err
+}
+
+// DeleteExpiredSessions removes sessions that expired before now and returns how many were removed
+func (r *UserRepository) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
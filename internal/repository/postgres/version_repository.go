@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// recordVersion saves a link's current destination, primary alias, expiry and preview
+// setting as its next version, unless they're unchanged since the latest one, and makes it
+// the link's current version. It runs in the transaction that changed the link, which holds
+// the link's row lock, so version numbers are handed out one at a time. A purged link
+// records nothing.
+func recordVersion(ctx context.Context, tx *sqlx.Tx, urlID int64, createdBy *int64) error {
+	query := `
+		WITH current AS (
+			SELECT id, original_url, ` + primaryAlias + ` AS custom_alias, expires_at, always_preview
+			FROM urls WHERE id = $1
+		), latest AS (
+			SELECT * FROM url_versions WHERE url_id = $1 ORDER BY version DESC LIMIT 1
+		), inserted AS (
+			INSERT INTO url_versions (url_id, version, original_url, custom_alias, expires_at, always_preview, created_by)
+			SELECT current.id, COALESCE((SELECT version FROM latest), 0) + 1,
+				current.original_url, current.custom_alias, current.expires_at, current.always_preview, $2
+			FROM current
+			WHERE NOT EXISTS (
+				SELECT 1 FROM latest
+				WHERE latest.original_url = current.original_url
+				AND latest.custom_alias IS NOT DISTINCT FROM current.custom_alias
+				AND latest.expires_at IS NOT DISTINCT FROM current.expires_at
+				AND latest.always_preview = current.always_preview
+			)
+			RETURNING url_id, version
+		)
+		UPDATE urls SET version = inserted.version
+		FROM inserted
+		WHERE urls.id = inserted.url_id
+	`
+	_, err := tx.ExecContext(ctx, query, urlID, createdBy)
+	return err
+}
+
+// ListVersions returns a URL's versions, newest first
+func (r *URLRepository) ListVersions(ctx context.Context, urlID int64) ([]*models.URLVersion, error) {
+	query := `
+		SELECT url_id, version, original_url, custom_alias, expires_at, always_preview, created_by, created_at
+		FROM url_versions
+		WHERE url_id = $1
+		ORDER BY version DESC
+	`
+
+	versions := []*models.URLVersion{}
+	if err := r.db.SelectContext(ctx, &versions, query, urlID); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// FindVersion retrieves one version of a URL
+func (r *URLRepository) FindVersion(ctx context.Context, urlID int64, version int) (*models.URLVersion, error) {
+	query := `
+		SELECT url_id, version, original_url, custom_alias, expires_at, always_preview, created_by, created_at
+		FROM url_versions
+		WHERE url_id = $1 AND version = $2
+	`
+
+	v := &models.URLVersion{}
+	err := r.db.GetContext(ctx, v, query, urlID, version)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrVersionNotFound
+	}
+	return v, err
+}
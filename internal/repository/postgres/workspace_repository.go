@@ -0,0 +1,262 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// WorkspaceRepository handles database operations for workspaces, their members and invitations
+type WorkspaceRepository struct {
+	db *sqlx.DB
+}
+
+// NewWorkspaceRepository creates a new WorkspaceRepository
+func NewWorkspaceRepository(db *sqlx.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// CreateWorkspace stores a workspace with ownerID as its first owner
+func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID int64) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO workspaces (name)
+		VALUES ($1)
+		RETURNING id, created_at
+	`, workspace.Name).Scan(&workspace.ID, &workspace.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, workspace.ID, ownerID, models.WorkspaceOwner)
+	if err != nil {
+		return err
+	}
+
+	workspace.Role = models.WorkspaceOwner
+	return tx.Commit()
+}
+
+// ListWorkspacesForUser returns the workspaces a user belongs to with their role in each
+func (r *WorkspaceRepository) ListWorkspacesForUser(ctx context.Context, userID int64) ([]*models.Workspace, error) {
+	query := `
+		SELECT workspaces.id, workspaces.name, workspaces.created_at, workspace_members.role
+		FROM workspaces
+		JOIN workspace_members ON workspace_members.workspace_id = workspaces.id
+		WHERE workspace_members.user_id = $1
+		ORDER BY workspaces.name, workspaces.id
+	`
+
+	workspaces := []*models.Workspace{}
+	err := r.db.SelectContext(ctx, &workspaces, query, userID)
+	return workspaces, err
+}
+
+// MemberRole returns a user's role in a workspace, or ErrMemberNotFound
+func (r *WorkspaceRepository) MemberRole(ctx context.Context, workspaceID, userID int64) (models.WorkspaceRole, error) {
+	var role models.WorkspaceRole
+	err := r.db.GetContext(ctx, &role, `
+		SELECT role FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID)
+	if err == sql.ErrNoRows {
+		return "", models.ErrMemberNotFound
+	}
+	return role, err
+}
+
+// ListMembers returns a workspace's members, owners first
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID int64) ([]*models.WorkspaceMember, error) {
+	query := `
+		SELECT workspace_members.workspace_id, workspace_members.user_id, users.email,
+			workspace_members.role, workspace_members.created_at
+		FROM workspace_members
+		JOIN users ON users.id = workspace_members.user_id
+		WHERE workspace_members.workspace_id = $1
+		ORDER BY CASE workspace_members.role
+			WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'editor' THEN 2 ELSE 3 END,
+			users.email
+	`
+
+	members := []*models.WorkspaceMember{}
+	err := r.db.SelectContext(ctx, &members, query, workspaceID)
+	return members, err
+}
+
+// keepsOwner guards member changes so that a workspace never loses its last owner. It only holds
+// under concurrent changes when run through changeMember, which locks the workspace first.
+const keepsOwner = `(role <> 'owner' OR (
+	SELECT COUNT(*) FROM workspace_members owners
+	WHERE owners.workspace_id = $1 AND owners.role = 'owner'
+) > 1)`
+
+// SetMemberRole changes a member's role. Demoting the last owner returns ErrLastOwner.
+func (r *WorkspaceRepository) SetMemberRole(ctx context.Context, workspaceID, userID int64, role models.WorkspaceRole) error {
+	query := `
+		UPDATE workspace_members SET role = $3
+		WHERE workspace_id = $1 AND user_id = $2 AND ($3 = 'owner' OR ` + keepsOwner + `)
+	`
+
+	return r.changeMember(ctx, workspaceID, userID, query, role)
+}
+
+// RemoveMember takes a user out of a workspace. Removing the last owner returns ErrLastOwner.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int64) error {
+	query := `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2 AND ` + keepsOwner
+
+	return r.changeMember(ctx, workspaceID, userID, query)
+}
+
+// changeMember runs a member update guarded by keepsOwner with the workspace row locked, so two
+// owners demoting or removing each other at once can't both see the other still in place
+func (r *WorkspaceRepository) changeMember(ctx context.Context, workspaceID, userID int64, query string, args ...interface{}) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked int64
+	err = tx.GetContext(ctx, &locked, `SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID)
+	if err == sql.ErrNoRows {
+		return models.ErrMemberNotFound
+	} else if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, append([]interface{}{workspaceID, userID}, args...)...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// Explain why nothing changed: the user isn't a member, or they're the last owner
+		var role models.WorkspaceRole
+		err := tx.GetContext(ctx, &role, `
+			SELECT role FROM workspace_members
+			WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID)
+		if err == sql.ErrNoRows {
+			return models.ErrMemberNotFound
+		} else if err != nil {
+			return err
+		}
+		return models.ErrLastOwner
+	}
+	return tx.Commit()
+}
+
+// CreateInvitation stores an invitation to a workspace
+func (r *WorkspaceRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	query := `
+		INSERT INTO workspace_invitations (workspace_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRowContext(ctx, query,
+		invitation.WorkspaceID,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+}
+
+// ListInvitations returns a workspace's pending, unexpired invitations
+func (r *WorkspaceRepository) ListInvitations(ctx context.Context, workspaceID int64) ([]*models.Invitation, error) {
+	query := `
+		SELECT id, workspace_id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at
+		FROM workspace_invitations
+		WHERE workspace_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	invitations := []*models.Invitation{}
+	err := r.db.SelectContext(ctx, &invitations, query, workspaceID)
+	return invitations, err
+}
+
+// DeleteInvitation revokes a pending invitation
+func (r *WorkspaceRepository) DeleteInvitation(ctx context.Context, workspaceID, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM workspace_invitations
+		WHERE workspace_id = $1 AND id = $2 AND accepted_at IS NULL
+	`, workspaceID, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds user to the workspace of the pending invitation with the given token hash
+// and returns the workspace ID. The invitation must have been sent to the user's email.
+func (r *WorkspaceRepository) AcceptInvitation(ctx context.Context, tokenHash string, user *models.User) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	invitation := &models.Invitation{}
+	err = tx.GetContext(ctx, invitation, `
+		SELECT id, workspace_id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at
+		FROM workspace_invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, tokenHash)
+	if err == sql.ErrNoRows {
+		return 0, models.ErrInvitationNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return 0, models.ErrInvitationMismatch
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, invitation.WorkspaceID, user.ID, invitation.Role)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return 0, models.ErrAlreadyMember
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE workspace_invitations SET accepted_at = NOW() WHERE id = $1`, invitation.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return invitation.WorkspaceID, tx.Commit()
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// Probes serves the health endpoints
+type Probes interface {
+	HealthCheck(w http.ResponseWriter, r *http.Request)
+	Livez(w http.ResponseWriter, r *http.Request)
+	Readyz(w http.ResponseWriter, r *http.Request)
+}
+
+// AdminHandler serves operational endpoints meant for a private listener: health, metrics
+// and pprof. A nil metrics handler leaves /metrics unregistered.
+func AdminHandler(probes Probes, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", probes.HealthCheck)
+	mux.HandleFunc("GET /livez", probes.Livez)
+	mux.HandleFunc("GET /readyz", probes.Readyz)
+	if metrics != nil {
+		mux.Handle("GET /metrics", metrics)
+	}
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// AuditRepository defines the interface for reading the audit log
+type AuditRepository interface {
+	List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// AuditService queries the audit log of changes to links
+type AuditService struct {
+	repo AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// List returns a page of audit entries matching the filter, newest first
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) (*models.AuditListResponse, error) {
+	if filter.Action != "" && !filter.Action.Valid() {
+		return nil, models.ErrInvalidAuditAction
+	}
+	if filter.ActorType != "" && !filter.ActorType.Valid() {
+		return nil, models.ErrInvalidAuditActorType
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuditListResponse{
+		Entries: entries,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	}, nil
+}
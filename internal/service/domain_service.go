@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/ownership"
+)
+
+// DomainRepository defines the interface for short domain storage
+type DomainRepository interface {
+	ListDomains(ctx context.Context) ([]*models.Domain, error)
+	FindDomainByHost(ctx context.Context, host string) (*models.Domain, error)
+	FindDomainByID(ctx context.Context, id int64) (*models.Domain, error)
+	CreateDomain(ctx context.Context, domain *models.Domain) error
+	RegisterPendingDomain(ctx context.Context, domain *models.Domain, pendingTTL time.Duration) error
+	MarkDomainVerified(ctx context.Context, id int64, token string) error
+	SetDomainActive(ctx context.Context, id int64, active bool) error
+}
+
+// OwnershipVerifier checks that a domain's owner published a verification token
+type OwnershipVerifier interface {
+	Verify(ctx context.Context, method, host, token string) error
+}
+
+// DomainService resolves short domains and manages the registered ones
+type DomainService struct {
+	repo          DomainRepository
+	verifier      OwnershipVerifier
+	workspaces    WorkspaceAuthorizer
+	defaultDomain *models.Domain
+	pendingTTL    time.Duration
+}
+
+// NewDomainService creates a new DomainService. BASE_URL is the default domain.
+func NewDomainService(repo DomainRepository, verifier OwnershipVerifier, workspaces WorkspaceAuthorizer, cfg *config.Config) *DomainService {
+	defaultDomain := &models.Domain{Scheme: "http", Active: true}
+	if u, err := url.Parse(cfg.BaseURL); err == nil {
+		defaultDomain.Host = normalizeDomainHost(u.Host)
+		if u.Scheme != "" {
+			defaultDomain.Scheme = u.Scheme
+		}
+	}
+
+	return &DomainService{
+		repo:          repo,
+		verifier:      verifier,
+		workspaces:    workspaces,
+		defaultDomain: defaultDomain,
+		pendingTTL:    cfg.DomainVerificationTTL,
+	}
+}
+
+// Default returns the deployment's own domain
+func (s *DomainService) Default() *models.Domain {
+	return s.defaultDomain
+}
+
+// Resolve returns the domain a request arrived on. Unknown hosts fall back to the
+// default domain, and disabled domains keep resolving their existing links.
+func (s *DomainService) Resolve(ctx context.Context, host string) (*models.Domain, error) {
+	host = normalizeDomainHost(host)
+	if host == "" || host == s.defaultDomain.Host {
+		return s.defaultDomain, nil
+	}
+
+	domain, err := s.repo.FindDomainByHost(ctx, host)
+	if err == models.ErrDomainNotFound || (err == nil && !domain.Verified()) {
+		return s.defaultDomain, nil
+	}
+	return domain, err
+}
+
+// ForLink returns the domain a stored link belongs to; a nil ID is the default domain
+func (s *DomainService) ForLink(ctx context.Context, domainID *int64) (*models.Domain, error) {
+	if domainID == nil {
+		return s.defaultDomain, nil
+	}
+	return s.repo.FindDomainByID(ctx, *domainID)
+}
+
+// ForNewLink returns the domain a new link may be created on by the actor, in workspaceID when
+// it isn't nil. An empty host is the default domain.
+func (s *DomainService) ForNewLink(ctx context.Context, host string, actor models.Actor, workspaceID *int64) (*models.Domain, error) {
+	host = normalizeDomainHost(host)
+	if host == "" || host == s.defaultDomain.Host {
+		return s.defaultDomain, nil
+	}
+
+	domain, err := s.repo.FindDomainByHost(ctx, host)
+	if err == models.ErrDomainNotFound {
+		return nil, models.ErrDomainNotAllowed
+	} else if err != nil {
+		return nil, err
+	}
+	if !domain.Active || !domain.Verified() || !usableBy(domain, actor, workspaceID) {
+		return nil, models.ErrDomainNotAllowed
+	}
+	return domain, nil
+}
+
+// OwnsHost reports whether host is the default domain or a verified domain, which this
+// deployment serves links on. The destination policy uses it to refuse links back to us.
+func (s *DomainService) OwnsHost(ctx context.Context, host string) (bool, error) {
+	host = normalizeDomainHost(host)
+	if host == s.defaultDomain.Host || host == hostnameOf(s.defaultDomain.Host) {
+		return true, nil
+	}
+
+	domain, err := s.repo.FindDomainByHost(ctx, host)
+	if err == models.ErrDomainNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return domain.Verified(), nil
+}
+
+// AllowCertificate reports whether a TLS certificate may be issued for host: the default
+// domain and verified domains qualify. It is used as the ACME host policy.
+func (s *DomainService) AllowCertificate(ctx context.Context, host string) error {
+	host = normalizeDomainHost(host)
+	if host == hostnameOf(s.defaultDomain.Host) {
+		return nil
+	}
+
+	domain, err := s.repo.FindDomainByHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("no certificate for %s: %w", host, err)
+	}
+	if !domain.Verified() {
+		return fmt.Errorf("no certificate for %s: %w", host, models.ErrDomainUnverified)
+	}
+	return nil
+}
+
+// AvailableDomains returns the domains the actor can create new links on, in workspaceID when it
+// isn't nil, starting with the default domain
+func (s *DomainService) AvailableDomains(ctx context.Context, actor models.Actor, workspaceID *int64) ([]*models.Domain, error) {
+	if workspaceID != nil {
+		if _, err := s.workspaces.Authorize(ctx, actor, *workspaceID, models.PermViewLinks); err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := s.repo.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	available := []*models.Domain{s.defaultDomain}
+	for _, d := range domains {
+		if d.Active && d.Verified() && usableBy(d, actor, workspaceID) {
+			available = append(available, d)
+		}
+	}
+	return available, nil
+}
+
+// usableBy reports whether the actor may put new links on a domain. Domains without an owner are
+// open to everyone; a workspace's domain takes links in that workspace, and a user's their own.
+func usableBy(domain *models.Domain, actor models.Actor, workspaceID *int64) bool {
+	switch {
+	case actor.Admin:
+		return true
+	case domain.OwnerWorkspaceID != nil:
+		return workspaceID != nil && *workspaceID == *domain.OwnerWorkspaceID
+	case domain.OwnerUserID != nil:
+		return actor.User != nil && actor.User.ID == *domain.OwnerUserID
+	}
+	return true
+}
+
+// ListDomains returns all registered domains
+func (s *DomainService) ListDomains(ctx context.Context) ([]*models.Domain, error) {
+	return s.repo.ListDomains(ctx)
+}
+
+// CreateDomain registers a trusted domain for new links, skipping ownership verification
+func (s *DomainService) CreateDomain(ctx context.Context, req models.CreateDomainRequest) (*models.Domain, error) {
+	domain, err := s.newDomain(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateDomain(ctx, domain); err != nil {
+		return nil, err
+	}
+	return domain, nil
+}
+
+// newDomain validates a registration request
+func (s *DomainService) newDomain(req models.CreateDomainRequest) (*models.Domain, error) {
+	domain := &models.Domain{
+		Host:             normalizeDomainHost(req.Host),
+		Scheme:           strings.ToLower(req.Scheme),
+		Active:           true,
+		OwnerWorkspaceID: req.WorkspaceID,
+	}
+	if domain.Scheme == "" {
+		domain.Scheme = "https"
+	}
+	if !validDomainHost(domain.Host) || (domain.Scheme != "http" && domain.Scheme != "https") {
+		return nil, models.ErrInvalidDomain
+	}
+	if domain.Host == s.defaultDomain.Host {
+		return nil, models.ErrDuplicateDomain
+	}
+	return domain, nil
+}
+
+// RegisterDomain records a customer's domain as pending and returns the token that proves
+// ownership. The domain belongs to the workspace named in the request, which the actor must be
+// able to manage domains of, or otherwise to the signed-in actor.
+func (s *DomainService) RegisterDomain(ctx context.Context, req models.CreateDomainRequest, actor models.Actor) (*models.DomainRegistration, error) {
+	if req.WorkspaceID != nil {
+		if _, err := s.workspaces.Authorize(ctx, actor, *req.WorkspaceID, models.PermManageDomains); err != nil {
+			return nil, err
+		}
+	} else if actor.User == nil {
+		return nil, models.ErrSignInRequired
+	}
+
+	domain, err := s.newDomain(req)
+	if err != nil {
+		return nil, err
+	}
+	if domain.OwnerWorkspaceID == nil {
+		domain.OwnerUserID = actor.UserID()
+	}
+
+	token, err := verificationToken()
+	if err != nil {
+		return nil, err
+	}
+	domain.VerificationToken = token
+
+	if err := s.repo.RegisterPendingDomain(ctx, domain, s.pendingTTL); err != nil {
+		return nil, err
+	}
+
+	return &models.DomainRegistration{
+		Domain:       domain,
+		Token:        token,
+		TXTRecord:    ownership.TXTRecord(domain.Host),
+		TXTValue:     ownership.TXTValue(token),
+		WellKnownURL: ownership.WellKnownURL(domain.Host),
+	}, nil
+}
+
+// VerifyDomain checks a pending domain's token with method ("dns" or "http") and opens it for
+// new links. Only the domain's owner, or an admin, may verify it.
+func (s *DomainService) VerifyDomain(ctx context.Context, id int64, method string, actor models.Actor) (*models.Domain, error) {
+	domain, err := s.repo.FindDomainByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeOwner(ctx, domain, actor); err != nil {
+		return nil, err
+	}
+	if domain.Verified() {
+		return domain, nil
+	}
+
+	if err := s.verifier.Verify(ctx, method, domain.Host, domain.VerificationToken); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkDomainVerified(ctx, id, domain.VerificationToken); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDomainByID(ctx, id)
+}
+
+// authorizeOwner checks that the actor may manage a registered domain. Other people's domains
+// are reported as not found so their IDs don't reveal anything.
+func (s *DomainService) authorizeOwner(ctx context.Context, domain *models.Domain, actor models.Actor) error {
+	if actor.Admin {
+		return nil
+	}
+	if domain.OwnerWorkspaceID != nil {
+		_, err := s.workspaces.Authorize(ctx, actor, *domain.OwnerWorkspaceID, models.PermManageDomains)
+		if err == models.ErrWorkspaceNotFound || err == models.ErrForbidden {
+			return models.ErrDomainNotFound
+		}
+		return err
+	}
+	if actor.User == nil {
+		return models.ErrSignInRequired
+	}
+	if domain.OwnerUserID == nil || *domain.OwnerUserID != actor.User.ID {
+		return models.ErrDomainNotFound
+	}
+	return nil
+}
+
+// SetDomainActive enables or disables new links on a domain
+func (s *DomainService) SetDomainActive(ctx context.Context, id int64, active bool) error {
+	return s.repo.SetDomainActive(ctx, id, active)
+}
+
+// normalizeDomainHost lowercases a host and drops a trailing dot, keeping any port
+func normalizeDomainHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(strings.TrimSuffix(h, "."), port)
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// hostnameOf strips any port from host
+func hostnameOf(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// verificationToken returns a random token for proving domain ownership
+func verificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validDomainHost checks that host is a bare host with an optional port
+func validDomainHost(host string) bool {
+	u, err := url.Parse("//" + host)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return u.Host == host && u.Path == "" && u.User == nil
+}
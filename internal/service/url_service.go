@@ -1,250 +1,853 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"net/url"
-	"time"
-
-	"github.com/rakheshkrishna2005/url-shortener/internal/config"
-	"github.com/rakheshkrishna2005/url-shortener/internal/models"
-	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
-)
-
-// URLRepository defines the interface for URL data access
-type URLRepository interface {
-	Store(ctx context.Context, url *models.URL) error
-	FindByShortCode(ctx context.Context, shortCode string) (*models.URL, error)
-	FindByID(ctx context.Context, id int64) (*models.URL, error)
-	FindByCustomAlias(ctx context.Context, alias string) (*models.URL, error)
-	Update(ctx context.Context, url *models.URL) error
-	Delete(ctx context.Context, id int64) error
-	RecordClick(ctx context.Context, event *models.ClickEvent) error
-	GetURLStats(ctx context.Context, urlID int64) (*models.URLStats, error)
-}
-
-// URLService handles the business logic for URL operations
-type URLService struct {
-	repo   URLRepository
-	config *config.Config
-}
-
-// NewURLService creates a new URLService
-func NewURLService(repo URLRepository, cfg *config.Config) *URLService {
-	return &URLService{
-		repo:   repo,
-		config: cfg,
-	}
-}
-
-// CreateShortURL creates a new shortened URL
-func (s *URLService) CreateShortURL(ctx context.Context, req models.CreateURLRequest, userIP string) (*models.CreateURLResponse, error) {
-	// Validate original URL
-	_, err := url.ParseRequestURI(req.OriginalURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid URL format: %w", err)
-	}
-
-	// Generate short code or use custom alias
-	var shortCode string
-	if req.CustomAlias != nil && *req.CustomAlias != "" {
-		// Check if alias is valid
-		if !utils.IsValidCustomAlias(*req.CustomAlias) {
-			return nil, fmt.Errorf("invalid custom alias: must be 3-50 alphanumeric characters")
-		}
-		
-		// Ensure custom alias doesn't exceed database limit
-		if len(*req.CustomAlias) > 10 {
-			return nil, fmt.Errorf("custom alias too long: must be 10 characters or less")
-		}
-
-		// Check if alias already exists
-		_, err := s.repo.FindByCustomAlias(ctx, *req.CustomAlias)
-		if err == nil {
-			return nil, models.ErrDuplicateAlias
-		} else if err != models.ErrURLNotFound {
-			return nil, err
-		}
-
-		shortCode = *req.CustomAlias
-	} else {
-		// Ensure short code length doesn't exceed 10
-		codeLen := s.config.ShortCodeLen
-		if codeLen > 10 {
-			codeLen = 10
-		}
-		
-		for i := 0; i < 5; i++ { // Try a few times in case of collision
-			shortCode, err = utils.GenerateShortCode(codeLen)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate short code: %w", err)
-			}
-
-			// Check if short code already exists
-			_, err := s.repo.FindByShortCode(ctx, shortCode)
-			if err == models.ErrURLNotFound {
-				break // This is a good short code
-			} else if err != nil {
-				return nil, err
-			}
-			// If we get here, short code exists, try again
-		}
-	}
-
-	// Calculate expiration time
-	var expiresAt *time.Time
-	if req.ExpiresIn != nil && *req.ExpiresIn > 0 {
-		exp := time.Now().Add(time.Duration(*req.ExpiresIn) * 24 * time.Hour)
-		expiresAt = &exp
-	} else if s.config.DefaultExpiry > 0 {
-		exp := time.Now().Add(s.config.DefaultExpiry)
-		expiresAt = &exp
-	}
-
-	// Create URL entity
-	urlEntity := &models.URL{
-		OriginalURL: req.OriginalURL,
-		ShortCode:   shortCode,
-		CustomAlias: req.CustomAlias,
-		ExpiresAt:   expiresAt,
-	}
-
-	if userIP != "" {
-		urlEntity.UserIP = &userIP
-	}
-
-	// Store in database
-	err = s.repo.Store(ctx, urlEntity)
-	if err != nil {
-		return nil, fmt.Errorf("failed to store URL: %w", err)
-	}
-
-	// Create response
-	shortURL := fmt.Sprintf("%s/%s", s.config.BaseURL, shortCode)
-	response := &models.CreateURLResponse{
-		ShortURL:    shortURL,
-		ShortCode:   shortCode,
-		OriginalURL: req.OriginalURL,
-		CustomAlias: req.CustomAlias,
-		ExpiresAt:   expiresAt,
-	}
-
-	return response, nil
-}
-
-// GetURL retrieves a URL by short code
-func (s *URLService) GetURL(ctx context.Context, shortCode string) (*models.URL, error) {
-	if !utils.IsValidShortCode(shortCode) {
-		return nil, models.ErrInvalidShortCode
-	}
-
-	url, err := s.repo.FindByShortCode(ctx, shortCode)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if URL has expired
-	if url.ExpiresAt != nil && time.Now().After(*url.ExpiresAt) {
-		return nil, models.ErrURLExpired
-	}
-
-	return url, nil
-}
-
-// GetURLByID retrieves a URL by ID
-func (s *URLService) GetURLByID(ctx context.Context, id int64) (*models.URLDetailResponse, error) {
-	url, err := s.repo.FindByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-
-	stats, err := s.repo.GetURLStats(ctx, id)
-	if err != nil {
-		// We can still return the URL even if stats retrieval fails
-		stats = &models.URLStats{
-			ClickCount: 0,
-		}
-	}
-
-	return &models.URLDetailResponse{
-		URL:   url,
-		Stats: stats,
-	}, nil
-}
-
-// UpdateURL updates a URL
-func (s *URLService) UpdateURL(ctx context.Context, id int64, originalURL string, customAlias *string, expiresIn *int) error {
-	urlModel, err := s.repo.FindByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	// Update original URL if provided
-	if originalURL != "" {
-		_, err := url.ParseRequestURI(originalURL)
-		if err != nil {
-			return fmt.Errorf("invalid URL format: %w", err)
-		}
-		urlModel.OriginalURL = originalURL
-	}
-
-	// Update custom alias if provided
-	if customAlias != nil {
-		if *customAlias == "" {
-			urlModel.CustomAlias = nil
-		} else {
-			if !utils.IsValidCustomAlias(*customAlias) {
-				return fmt.Errorf("invalid custom alias: must be 3-50 alphanumeric characters")
-			}
-
-			// Check if alias already exists and doesn't belong to this URL
-			existingURL, err := s.repo.FindByCustomAlias(ctx, *customAlias)
-			if err == nil && existingURL.ID != id {
-				return models.ErrDuplicateAlias
-			} else if err != nil && err != models.ErrURLNotFound {
-				return err
-			}
-
-			urlModel.CustomAlias = customAlias
-		}
-	}
-
-	// Update expiry if provided
-	if expiresIn != nil {
-		if *expiresIn <= 0 {
-			urlModel.ExpiresAt = nil
-		} else {
-			exp := time.Now().Add(time.Duration(*expiresIn) * 24 * time.Hour)
-			urlModel.ExpiresAt = &exp
-		}
-	}
-
-	return s.repo.Update(ctx, urlModel)
-}
-
-// DeleteURL deletes a URL by ID
-func (s *URLService) DeleteURL(ctx context.Context, id int64) error {
-	return s.repo.Delete(ctx, id)
-}
-
-// RecordClick records a click event for a URL
-func (s *URLService) RecordClick(ctx context.Context, urlID int64, referer, userAgent, ipAddress string) error {
-	event := &models.ClickEvent{
-		URLID:      urlID,
-		AccessedAt: time.Now(),
-	}
-
-	if referer != "" {
-		event.Referer = &referer
-	}
-
-	if userAgent != "" {
-		event.UserAgent = &userAgent
-	}
-
-	if ipAddress != "" {
-		event.IPAddress = &ipAddress
-	}
-
-	return s.repo.RecordClick(ctx, event)
-}
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+// URLRepository defines the interface for URL data access
+type URLRepository interface {
+	Store(ctx context.Context, url *models.URL, limits models.QuotaLimits, audit *models.AuditEntry) error
+	FindByShortCode(ctx context.Context, domainID *int64, shortCode string) (*models.URL, error)
+	FindByID(ctx context.Context, id int64) (*models.URL, error)
+	FindDeleted(ctx context.Context, id int64) (*models.URL, error)
+	FindByAlias(ctx context.Context, domainID *int64, alias string) (*models.URL, error)
+	CodeTaken(ctx context.Context, domainID *int64, code string) (bool, error)
+	ListAliases(ctx context.Context, urlID int64) ([]*models.URLAlias, error)
+	AddAlias(ctx context.Context, urlID int64, alias string, audit *models.AuditEntry) error
+	RenameAlias(ctx context.Context, urlID int64, oldAlias, newAlias string, redirectUntil time.Time, audit *models.AuditEntry) error
+	RemoveAlias(ctx context.Context, urlID int64, alias string, audit *models.AuditEntry) error
+	ListVersions(ctx context.Context, urlID int64) ([]*models.URLVersion, error)
+	FindVersion(ctx context.Context, urlID int64, version int) (*models.URLVersion, error)
+	Update(ctx context.Context, url *models.URL, ifVersion int, aliasRedirectUntil time.Time, limits models.QuotaLimits, audit *models.AuditEntry) error
+	UpdateStatus(ctx context.Context, id int64, status models.URLStatus, reason *string, audit *models.AuditEntry) error
+	Delete(ctx context.Context, id int64, audit *models.AuditEntry) error
+	Undelete(ctx context.Context, url *models.URL, limits models.QuotaLimits, audit *models.AuditEntry) error
+	PurgeDeleted(ctx context.Context, cutoff time.Time, limit int) (int, error)
+	RecordClick(ctx context.Context, event *models.ClickEvent) error
+	GetURLStats(ctx context.Context, urlID int64) (*models.URLStats, error)
+	GetMetadata(ctx context.Context, urlID int64) (*models.URLMetadata, error)
+	GetHealth(ctx context.Context, urlID int64) (*models.URLHealth, error)
+	GetHealthForURLs(ctx context.Context, urlIDs []int64) (map[int64]*models.URLHealth, error)
+	GetStatsForURLs(ctx context.Context, urlIDs []int64, since time.Time) (map[int64]*models.URLStats, error)
+	List(ctx context.Context, filter models.URLFilter) ([]*models.URL, error)
+}
+
+// DestinationPolicy decides whether a destination URL may be shortened or served
+type DestinationPolicy interface {
+	Check(ctx context.Context, rawURL string) error
+	CheckStatic(ctx context.Context, rawURL string) error
+}
+
+// DomainResolver picks the short domain for lookups and new links
+type DomainResolver interface {
+	Resolve(ctx context.Context, host string) (*models.Domain, error)
+	ForNewLink(ctx context.Context, host string, actor models.Actor, workspaceID *int64) (*models.Domain, error)
+	ForLink(ctx context.Context, domainID *int64) (*models.Domain, error)
+}
+
+// CodeGenerator produces candidate short codes and learns from collisions
+type CodeGenerator interface {
+	Generate(ctx context.Context) (string, error)
+	Record(code string, collided bool)
+}
+
+// CodePolicy rejects codes and aliases that are reserved or offensive
+type CodePolicy interface {
+	Check(code string) error
+}
+
+// maxCodeAttempts bounds how many generated codes are tried before giving up
+const maxCodeAttempts = 10
+
+// MetadataScheduler queues a background fetch of a destination's title, description and icons
+type MetadataScheduler interface {
+	Enqueue(urlID int64, rawURL string)
+}
+
+// WorkspaceAuthorizer checks an actor's role in a workspace against a permission
+type WorkspaceAuthorizer interface {
+	Authorize(ctx context.Context, actor models.Actor, workspaceID int64, perm models.Permission) (models.WorkspaceRole, error)
+}
+
+// QuotaResolver looks up an owner's quota limits and meters clicks against them
+type QuotaResolver interface {
+	Limits(ctx context.Context, owner models.QuotaOwner) (models.QuotaLimits, error)
+	CountClick(ctx context.Context, u *models.URL) error
+}
+
+// URLService handles the business logic for URL operations
+type URLService struct {
+	repo       URLRepository
+	policy     DestinationPolicy
+	codes      CodeGenerator
+	codePolicy CodePolicy
+	alphabet   *utils.Alphabet
+	domains    DomainResolver
+	workspaces WorkspaceAuthorizer
+	quotas     QuotaResolver
+	metadata   MetadataScheduler
+	config     *config.Config
+}
+
+// NewURLService creates a new URLService. A nil metadata scheduler disables metadata fetching.
+func NewURLService(repo URLRepository, policy DestinationPolicy, codes CodeGenerator, codePolicy CodePolicy, alphabet *utils.Alphabet, domains DomainResolver, workspaces WorkspaceAuthorizer, quotas QuotaResolver, metadata MetadataScheduler, cfg *config.Config) *URLService {
+	return &URLService{
+		repo:       repo,
+		policy:     policy,
+		codes:      codes,
+		codePolicy: codePolicy,
+		alphabet:   alphabet,
+		domains:    domains,
+		workspaces: workspaces,
+		quotas:     quotas,
+		metadata:   metadata,
+		config:     cfg,
+	}
+}
+
+// CreateShortURL creates a new shortened URL. A signed-in actor owns the link, or their
+// workspace does when the request names one they can edit links in.
+func (s *URLService) CreateShortURL(ctx context.Context, req models.CreateURLRequest, userIP string, actor models.Actor) (*models.CreateURLResponse, error) {
+	if req.WorkspaceID != nil {
+		if _, err := s.workspaces.Authorize(ctx, actor, *req.WorkspaceID, models.PermEditLinks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate original URL
+	_, err := url.ParseRequestURI(req.OriginalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	// Reject destinations the policy doesn't allow
+	if err := s.policy.Check(ctx, req.OriginalURL); err != nil {
+		return nil, err
+	}
+
+	domain, err := s.domains.ForNewLink(ctx, req.Domain, actor, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate expiration time
+	var expiresAt *time.Time
+	if req.ExpiresIn != nil && *req.ExpiresIn > 0 {
+		exp := time.Now().Add(time.Duration(*req.ExpiresIn) * 24 * time.Hour)
+		expiresAt = &exp
+	} else if s.config.DefaultExpiry > 0 {
+		exp := time.Now().Add(s.config.DefaultExpiry)
+		expiresAt = &exp
+	}
+
+	// Aliases are stored in the alphabet's normalized form
+	if req.CustomAlias != nil {
+		alias := s.alphabet.Normalize(*req.CustomAlias)
+		req.CustomAlias = &alias
+	}
+
+	// Create URL entity
+	urlEntity := &models.URL{
+		OriginalURL:   req.OriginalURL,
+		DomainID:      domain.Ref(),
+		CustomAlias:   req.CustomAlias,
+		ExpiresAt:     expiresAt,
+		AlwaysPreview: req.AlwaysPreview,
+		UserID:        actor.UserID(),
+		WorkspaceID:   req.WorkspaceID,
+	}
+
+	if userIP != "" {
+		urlEntity.UserIP = &userIP
+	}
+
+	// Every URL gets a generated code; a custom alias is stored alongside it
+	if req.CustomAlias != nil && *req.CustomAlias != "" {
+		if err := s.validateAlias(*req.CustomAlias); err != nil {
+			return nil, err
+		}
+	} else {
+		urlEntity.CustomAlias = nil
+	}
+
+	// Links with an owner count against the owner's quotas
+	var limits models.QuotaLimits
+	if owner, ok := urlEntity.QuotaOwner(); ok {
+		if limits, err = s.quotas.Limits(ctx, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	audit := models.NewAuditEntry(models.AuditURLCreated, actor)
+	if err := s.storeWithGeneratedCode(ctx, urlEntity, limits, audit); err != nil {
+		return nil, err
+	}
+	shortCode := urlEntity.ShortCode
+
+	if s.metadata != nil {
+		s.metadata.Enqueue(urlEntity.ID, urlEntity.OriginalURL)
+	}
+
+	// Share the alias when there is one
+	linkCode := shortCode
+	if urlEntity.CustomAlias != nil {
+		linkCode = *urlEntity.CustomAlias
+	}
+
+	// Create response
+	shortURL := fmt.Sprintf("%s/%s", domain.BaseURL(), linkCode)
+	response := &models.CreateURLResponse{
+		ShortURL:      shortURL,
+		PreviewURL:    shortURL + "+",
+		ShortCode:     shortCode,
+		OriginalURL:   req.OriginalURL,
+		CustomAlias:   urlEntity.CustomAlias,
+		ExpiresAt:     expiresAt,
+		AlwaysPreview: req.AlwaysPreview,
+	}
+
+	return response, nil
+}
+
+// storeWithGeneratedCode stores a URL under a freshly generated code, retrying with a new code on collision
+func (s *URLService) storeWithGeneratedCode(ctx context.Context, urlEntity *models.URL, limits models.QuotaLimits, audit *models.AuditEntry) error {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		code, err := s.codes.Generate(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate short code: %w", err)
+		}
+
+		// Skip codes that spell something we don't want to hand out
+		if s.codePolicy.Check(code) != nil {
+			continue
+		}
+
+		urlEntity.ShortCode = code
+		err = s.repo.Store(ctx, urlEntity, limits, audit)
+		if err == nil {
+			s.codes.Record(code, false)
+			return nil
+		}
+		if err == models.ErrDuplicateAlias || err == models.ErrLinkQuotaExceeded || err == models.ErrActiveLinkQuotaExceeded {
+			return err
+		}
+		if err != models.ErrDuplicateShortCode {
+			return fmt.Errorf("failed to store URL: %w", err)
+		}
+		s.codes.Record(code, true)
+	}
+
+	return models.ErrShortCodeExhausted
+}
+
+// CheckAlias reports whether the actor could register a custom alias on a domain right now,
+// for a link in workspaceID when it isn't nil
+func (s *URLService) CheckAlias(ctx context.Context, host, alias string, actor models.Actor, workspaceID *int64) (*models.AliasAvailability, error) {
+	if workspaceID != nil {
+		if _, err := s.workspaces.Authorize(ctx, actor, *workspaceID, models.PermEditLinks); err != nil {
+			return nil, err
+		}
+	}
+
+	alias = s.alphabet.Normalize(alias)
+	availability := &models.AliasAvailability{Alias: alias}
+
+	if err := s.validateAlias(alias); err != nil {
+		availability.Reason = err.Error()
+		return availability, nil
+	}
+
+	domain, err := s.domains.ForNewLink(ctx, host, actor, workspaceID)
+	if err == models.ErrDomainNotAllowed {
+		availability.Reason = err.Error()
+		return availability, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Aliases share their namespace with short codes, including those of links in the trash
+	taken, err := s.repo.CodeTaken(ctx, domain.Ref(), alias)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		availability.Reason = models.ErrDuplicateAlias.Error()
+		return availability, nil
+	}
+
+	availability.Available = true
+	return availability, nil
+}
+
+// validateAlias checks an alias's format and the code policy
+func (s *URLService) validateAlias(alias string) error {
+	if !utils.IsValidCustomAlias(alias) {
+		return models.ErrInvalidAlias
+	}
+	return s.codePolicy.Check(alias)
+}
+
+// GetURL retrieves a URL by short code or alias on the domain the request arrived on
+func (s *URLService) GetURL(ctx context.Context, host, shortCode string) (*models.URL, error) {
+	if !s.alphabet.IsValid(shortCode) && !utils.IsValidCustomAlias(shortCode) {
+		return nil, models.ErrInvalidShortCode
+	}
+
+	domain, err := s.domains.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.repo.FindByShortCode(ctx, domain.Ref(), shortCode)
+	if err == models.ErrURLNotFound {
+		url, err = s.repo.FindByAlias(ctx, domain.Ref(), shortCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if URL has expired
+	if url.ExpiresAt != nil && time.Now().After(*url.ExpiresAt) {
+		return nil, models.ErrURLExpired
+	}
+
+	// Only active URLs redirect
+	if url.Status != models.StatusActive {
+		return nil, models.ErrURLDisabled
+	}
+
+	// Re-check the destination, as the policy or threat list may have changed since creation
+	if err := s.policy.CheckStatic(ctx, url.OriginalURL); err != nil {
+		reason := err.Error()
+		audit := &models.AuditEntry{Action: models.AuditURLStatusChanged, ActorType: models.AuditActorSystem}
+		if err := s.repo.UpdateStatus(ctx, url.ID, models.StatusFlagged, &reason, audit); err != nil {
+			log.Printf("Failed to flag URL %d: %v", url.ID, err)
+		}
+		return nil, models.ErrURLDisabled
+	}
+
+	return url, nil
+}
+
+// GetURLPreview builds the interstitial shown before following a link.
+// It applies the same expiry, status and policy checks as GetURL.
+func (s *URLService) GetURLPreview(ctx context.Context, host, shortCode string) (*models.URLPreview, error) {
+	urlModel, err := s.GetURL(ctx, host, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.PreviewFor(ctx, urlModel), nil
+}
+
+// PreviewFor builds the interstitial for a URL that has already been resolved
+func (s *URLService) PreviewFor(ctx context.Context, urlModel *models.URL) *models.URLPreview {
+	preview := &models.URLPreview{
+		ShortCode:     urlModel.ShortCode,
+		OriginalURL:   urlModel.OriginalURL,
+		CreatedAt:     urlModel.CreatedAt,
+		AlwaysPreview: urlModel.AlwaysPreview,
+	}
+
+	if parsed, err := url.Parse(urlModel.OriginalURL); err == nil {
+		preview.Domain = parsed.Hostname()
+	}
+
+	// The preview is still useful without a click count
+	if stats, err := s.repo.GetURLStats(ctx, urlModel.ID); err == nil {
+		preview.ClickCount = stats.ClickCount
+	}
+
+	return preview
+}
+
+// findForActor loads a URL and checks the actor may use perm on it
+func (s *URLService) findForActor(ctx context.Context, id int64, actor models.Actor, perm models.Permission) (*models.URL, error) {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, u, actor, perm); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// authorize checks the actor may use perm on a URL. Workspace links follow the actor's
+// role in the workspace and personal links belong to the user who created them; links
+// created anonymously stay open, as they always have been. Links the actor can't see
+// at all are reported as not found.
+func (s *URLService) authorize(ctx context.Context, u *models.URL, actor models.Actor, perm models.Permission) error {
+	switch {
+	case actor.Admin:
+		return nil
+	case u.WorkspaceID != nil:
+		_, err := s.workspaces.Authorize(ctx, actor, *u.WorkspaceID, perm)
+		if err == models.ErrWorkspaceNotFound || err == models.ErrSignInRequired {
+			return models.ErrURLNotFound
+		}
+		return err
+	case u.UserID != nil:
+		if actor.User == nil || actor.User.ID != *u.UserID {
+			return models.ErrURLNotFound
+		}
+	}
+	return nil
+}
+
+// GetURLByID retrieves a URL by ID
+func (s *URLService) GetURLByID(ctx context.Context, id int64, actor models.Actor) (*models.URLDetailResponse, error) {
+	url, err := s.findForActor(ctx, id, actor, models.PermViewLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	// We can still return the URL even if stats retrieval fails
+	stats := &models.URLStats{Daily: fillDays(nil)}
+	if statsByID, err := s.repo.GetStatsForURLs(ctx, []int64{id}, statsSince()); err == nil {
+		stats = statsByID[id]
+		stats.Daily = fillDays(stats.Daily)
+	}
+
+	// Metadata is filled in asynchronously and may not exist yet
+	metadata, err := s.repo.GetMetadata(ctx, id)
+	if err != nil {
+		metadata = nil
+	}
+
+	// Health is only known once the checker has visited the link
+	health, err := s.repo.GetHealth(ctx, id)
+	if err != nil {
+		health = nil
+	}
+
+	aliases, err := s.repo.ListAliases(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	shortURL, err := s.shortURL(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.URLDetailResponse{
+		URL:      url,
+		ShortURL: shortURL,
+		Stats:    stats,
+		Metadata: metadata,
+		Health:   health,
+		Aliases:  aliases,
+	}, nil
+}
+
+// ListURLs retrieves a page of URLs with their latest health check results. Only admins
+// may list across owners; anyone else must scope the filter to links they can view.
+func (s *URLService) ListURLs(ctx context.Context, filter models.URLFilter, actor models.Actor) (*models.URLListResponse, error) {
+	switch {
+	case actor.Admin:
+	case filter.WorkspaceID != 0:
+		if _, err := s.workspaces.Authorize(ctx, actor, filter.WorkspaceID, models.PermViewLinks); err != nil {
+			return nil, err
+		}
+	case filter.UserID != 0:
+		if actor.User == nil || actor.User.ID != filter.UserID {
+			return nil, models.ErrForbidden
+		}
+	case filter.UserIP != "":
+		// Addresses are shared and easy to claim, so only admins list links by address
+		return nil, models.ErrForbidden
+	case actor.User == nil:
+		return nil, models.ErrSignInRequired
+	default:
+		return nil, models.ErrForbidden
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, models.ErrInvalidStatus
+	}
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.MinFailures = s.config.LinkCheckFailureThreshold
+
+	urls, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(urls))
+	for i, u := range urls {
+		ids[i] = u.ID
+	}
+	health, err := s.repo.GetHealthForURLs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.repo.GetStatsForURLs(ctx, ids, statsSince())
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[int64]*models.Domain)
+	items := make([]*models.URLDetailResponse, len(urls))
+	for i, u := range urls {
+		shortURL, err := s.shortURL(ctx, u, domains)
+		if err != nil {
+			return nil, err
+		}
+		stats[u.ID].Daily = fillDays(stats[u.ID].Daily)
+		items[i] = &models.URLDetailResponse{URL: u, ShortURL: shortURL, Stats: stats[u.ID], Health: health[u.ID]}
+	}
+
+	return &models.URLListResponse{
+		URLs:   items,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// shortURL returns a link's short URL on its domain, preferring its alias. Domains
+// are looked up once per call when a cache map is passed.
+func (s *URLService) shortURL(ctx context.Context, u *models.URL, cache map[int64]*models.Domain) (string, error) {
+	var domain *models.Domain
+	if u.DomainID != nil && cache != nil {
+		domain = cache[*u.DomainID]
+	}
+	if domain == nil {
+		var err error
+		if domain, err = s.domains.ForLink(ctx, u.DomainID); err != nil {
+			return "", err
+		}
+		if u.DomainID != nil && cache != nil {
+			cache[*u.DomainID] = domain
+		}
+	}
+
+	code := u.ShortCode
+	if u.CustomAlias != nil {
+		code = *u.CustomAlias
+	}
+	return domain.BaseURL() + "/" + code, nil
+}
+
+// statsSince returns the start of the first day included in daily click stats
+func statsSince() time.Time {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return today.AddDate(0, 0, -(models.StatsDays - 1))
+}
+
+// fillDays returns one entry per stats day, with zero clicks for days missing from daily
+func fillDays(daily []models.DailyClicks) []models.DailyClicks {
+	clicks := make(map[string]int, len(daily))
+	for _, d := range daily {
+		clicks[d.Day.Format(time.DateOnly)] = d.Clicks
+	}
+
+	days := make([]models.DailyClicks, models.StatsDays)
+	for i, day := 0, statsSince(); i < models.StatsDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		days[i] = models.DailyClicks{Day: day, Clicks: clicks[day.Format(time.DateOnly)]}
+	}
+	return days
+}
+
+// ReplaceURL replaces a URL's editable fields with edit. A non-zero ifVersion makes the
+// change apply only to that version of the URL; it fails with ErrVersionMismatch otherwise.
+func (s *URLService) ReplaceURL(ctx context.Context, id int64, edit models.URLEdit, ifVersion int, actor models.Actor) (*models.URL, error) {
+	urlModel, err := s.findForActor(ctx, id, actor, models.PermEditLinks)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyEdit(ctx, urlModel, edit, ifVersion, models.NewAuditEntry(models.AuditURLUpdated, actor))
+}
+
+// patchAttempts is how many times a patch without If-Match is reapplied when another edit
+// lands between reading the URL and saving it
+const patchAttempts = 3
+
+// PatchURL applies a JSON merge patch (RFC 7396) to a URL's editable fields. With a
+// non-zero ifVersion it applies only to that version of the URL; without one it applies
+// to the latest, so fields the patch leaves out keep any concurrent change.
+func (s *URLService) PatchURL(ctx context.Context, id int64, patch []byte, ifVersion int, actor models.Actor) (*models.URL, error) {
+	for attempt := 1; ; attempt++ {
+		urlModel, err := s.findForActor(ctx, id, actor, models.PermEditLinks)
+		if err != nil {
+			return nil, err
+		}
+
+		edit, err := patchEdit(urlModel.Edit(), patch)
+		if err != nil {
+			return nil, err
+		}
+
+		expected := ifVersion
+		if expected == 0 {
+			expected = urlModel.Version
+		}
+		updated, err := s.applyEdit(ctx, urlModel, edit, expected, models.NewAuditEntry(models.AuditURLUpdated, actor))
+		if err == models.ErrVersionMismatch && ifVersion == 0 && attempt < patchAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// patchEdit applies a merge patch to a URL's editable fields. Patches that aren't an
+// object of those fields are ErrInvalidPatch.
+func patchEdit(edit models.URLEdit, patch []byte) (models.URLEdit, error) {
+	doc, err := json.Marshal(edit)
+	if err != nil {
+		return edit, err
+	}
+	merged, err := utils.MergePatch(doc, patch)
+	if err != nil {
+		return edit, models.ErrInvalidPatch
+	}
+
+	var patched models.URLEdit
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return edit, models.ErrInvalidPatch
+	}
+	return patched, nil
+}
+
+// applyEdit checks and saves a URL's new editable fields, returning the URL as saved. A
+// changed destination goes through the policy and a new alias through the alias checks;
+// the previous alias keeps redirecting for the grace period. Extending an expired link
+// counts against its owner's active link limit again.
+func (s *URLService) applyEdit(ctx context.Context, urlModel *models.URL, edit models.URLEdit, ifVersion int, audit *models.AuditEntry) (*models.URL, error) {
+	// A stale If-Match fails before any of the checks
+	if ifVersion != 0 && ifVersion != urlModel.Version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	destinationChanged := edit.OriginalURL != urlModel.OriginalURL
+	if destinationChanged {
+		if _, err := url.ParseRequestURI(edit.OriginalURL); err != nil {
+			return nil, fmt.Errorf("%w: %v", models.ErrInvalidURL, err)
+		}
+		if err := s.policy.Check(ctx, edit.OriginalURL); err != nil {
+			return nil, err
+		}
+	}
+
+	var alias *string
+	if edit.CustomAlias != nil && *edit.CustomAlias != "" {
+		normalized := s.alphabet.Normalize(*edit.CustomAlias)
+		if current := urlModel.CustomAlias; current == nil || *current != normalized {
+			if err := s.validateAlias(normalized); err != nil {
+				return nil, err
+			}
+		}
+		alias = &normalized
+	}
+
+	updated := *urlModel
+	updated.OriginalURL = edit.OriginalURL
+	updated.CustomAlias = alias
+	updated.ExpiresAt = edit.ExpiresAt
+	updated.AlwaysPreview = edit.AlwaysPreview
+
+	var limits models.QuotaLimits
+	if owner, ok := urlModel.QuotaOwner(); ok && urlModel.ExpiredAt(time.Now()) {
+		var err error
+		if limits, err = s.quotas.Limits(ctx, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, &updated, ifVersion, time.Now().Add(s.config.AliasGracePeriod), limits, audit); err != nil {
+		return nil, err
+	}
+
+	// The old metadata describes the previous destination
+	if destinationChanged && s.metadata != nil {
+		s.metadata.Enqueue(updated.ID, updated.OriginalURL)
+	}
+
+	return &updated, nil
+}
+
+// ListVersions returns a URL's saved versions, newest first
+func (s *URLService) ListVersions(ctx context.Context, id int64, actor models.Actor) ([]*models.URLVersion, error) {
+	if _, err := s.findForActor(ctx, id, actor, models.PermViewLinks); err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(ctx, id)
+}
+
+// RestoreVersion rolls a URL back to one of its versions, as a new version. The destination
+// and alias being restored go through the same checks as an edit, since the policy may have
+// changed or the alias been taken since; the expiry is restored as the time it was. A
+// non-zero ifVersion works as in ReplaceURL.
+func (s *URLService) RestoreVersion(ctx context.Context, id int64, version, ifVersion int, actor models.Actor) (*models.URL, error) {
+	urlModel, err := s.findForActor(ctx, id, actor, models.PermEditLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.FindVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyEdit(ctx, urlModel, v.Edit(), ifVersion, models.NewAuditEntry(models.AuditURLRestored, actor))
+}
+
+// ListAliases returns a URL's aliases, including renamed ones that still redirect
+func (s *URLService) ListAliases(ctx context.Context, id int64, actor models.Actor) ([]*models.URLAlias, error) {
+	if _, err := s.findForActor(ctx, id, actor, models.PermViewLinks); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAliases(ctx, id)
+}
+
+// AddAlias gives a URL another alias
+func (s *URLService) AddAlias(ctx context.Context, id int64, alias string, actor models.Actor) error {
+	if _, err := s.findForActor(ctx, id, actor, models.PermEditLinks); err != nil {
+		return err
+	}
+
+	alias = s.alphabet.Normalize(alias)
+	if err := s.validateAlias(alias); err != nil {
+		return err
+	}
+	return s.repo.AddAlias(ctx, id, alias, models.NewAuditEntry(models.AuditAliasAdded, actor))
+}
+
+// RenameAlias replaces one of a URL's aliases. The old alias keeps redirecting for the grace period.
+func (s *URLService) RenameAlias(ctx context.Context, id int64, oldAlias, newAlias string, actor models.Actor) error {
+	if _, err := s.findForActor(ctx, id, actor, models.PermEditLinks); err != nil {
+		return err
+	}
+
+	newAlias = s.alphabet.Normalize(newAlias)
+	if err := s.validateAlias(newAlias); err != nil {
+		return err
+	}
+	return s.repo.RenameAlias(ctx, id, oldAlias, newAlias, time.Now().Add(s.config.AliasGracePeriod), models.NewAuditEntry(models.AuditAliasRenamed, actor))
+}
+
+// RemoveAlias stops one of a URL's aliases from resolving
+func (s *URLService) RemoveAlias(ctx context.Context, id int64, alias string, actor models.Actor) error {
+	if _, err := s.findForActor(ctx, id, actor, models.PermEditLinks); err != nil {
+		return err
+	}
+	return s.repo.RemoveAlias(ctx, id, alias, models.NewAuditEntry(models.AuditAliasRemoved, actor))
+}
+
+// SetURLStatus changes the moderation status of a URL
+func (s *URLService) SetURLStatus(ctx context.Context, id int64, status models.URLStatus, reason *string, actor models.Actor) error {
+	if !status.Valid() {
+		return models.ErrInvalidStatus
+	}
+	if reason != nil && *reason == "" {
+		reason = nil
+	}
+
+	return s.repo.UpdateStatus(ctx, id, status, reason, models.NewAuditEntry(models.AuditURLStatusChanged, actor))
+}
+
+// DeleteURL moves a URL to the trash. It stops redirecting, but keeps its codes and analytics
+// and can be restored until it's purged.
+func (s *URLService) DeleteURL(ctx context.Context, id int64, actor models.Actor) error {
+	if _, err := s.findForActor(ctx, id, actor, models.PermEditLinks); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id, models.NewAuditEntry(models.AuditURLDeleted, actor))
+}
+
+// RestoreURL takes a URL out of the trash. It counts against its owner's active link limit again.
+func (s *URLService) RestoreURL(ctx context.Context, id int64, actor models.Actor) error {
+	urlModel, err := s.repo.FindDeleted(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, urlModel, actor, models.PermEditLinks); err != nil {
+		return err
+	}
+
+	var limits models.QuotaLimits
+	if owner, ok := urlModel.QuotaOwner(); ok {
+		if limits, err = s.quotas.Limits(ctx, owner); err != nil {
+			return err
+		}
+	}
+	return s.repo.Undelete(ctx, urlModel, limits, models.NewAuditEntry(models.AuditURLUndeleted, actor))
+}
+
+// trashPurgeBatch is how many links one purge transaction deletes
+const trashPurgeBatch = 100
+
+// RunTrashPurge permanently deletes links that have been in the trash longer than the
+// retention period, every interval until ctx is canceled
+func (s *URLService) RunTrashPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cutoff := time.Now().Add(-s.config.TrashRetention)
+		total := 0
+		for {
+			n, err := s.repo.PurgeDeleted(ctx, cutoff, trashPurgeBatch)
+			if err != nil {
+				log.Printf("Failed to purge deleted URLs: %v", err)
+				break
+			}
+			total += n
+			if n < trashPurgeBatch {
+				break
+			}
+		}
+		if total > 0 {
+			log.Printf("Purged %d deleted URLs", total)
+		}
+	}
+}
+
+// AllowClick counts a click on a link against its owner's monthly quota before it redirects.
+// It returns ErrClickQuotaExceeded once the owner has used up the month's clicks.
+func (s *URLService) AllowClick(ctx context.Context, u *models.URL) error {
+	return s.quotas.CountClick(ctx, u)
+}
+
+// RecordClick records a click event for a URL
+func (s *URLService) RecordClick(ctx context.Context, urlID int64, referer, userAgent, ipAddress string) error {
+	event := &models.ClickEvent{
+		URLID:      urlID,
+		AccessedAt: time.Now(),
+	}
+
+	if referer != "" {
+		event.Referer = &referer
+	}
+
+	if userAgent != "" {
+		event.UserAgent = &userAgent
+	}
+
+	if ipAddress != "" {
+		event.IPAddress = &ipAddress
+	}
+
+	return s.repo.RecordClick(ctx, event)
+}
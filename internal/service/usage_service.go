@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// UsageRepository defines the interface for usage counters and workspace quota storage
+type UsageRepository interface {
+	CountClick(ctx context.Context, owner models.QuotaOwner, limit int64) error
+	GetUsage(ctx context.Context, owner models.QuotaOwner) (*models.Usage, error)
+	GetQuotaOverrides(ctx context.Context, workspaceID int64) (*models.QuotaOverrides, error)
+	SetQuotaOverrides(ctx context.Context, workspaceID int64, overrides *models.QuotaOverrides) error
+}
+
+// UsageService resolves quota limits, meters clicks and reports usage. Link creation is
+// metered by the URL repository as it stores each link.
+type UsageService struct {
+	repo       UsageRepository
+	workspaces WorkspaceAuthorizer
+	defaults   models.QuotaLimits
+}
+
+// NewUsageService creates a new UsageService
+func NewUsageService(repo UsageRepository, workspaces WorkspaceAuthorizer, cfg *config.Config) *UsageService {
+	return &UsageService{
+		repo:       repo,
+		workspaces: workspaces,
+		defaults: models.QuotaLimits{
+			LinksPerMonth:  cfg.QuotaLinksPerMonth,
+			ActiveLinks:    cfg.QuotaActiveLinks,
+			ClicksPerMonth: cfg.QuotaClicksPerMonth,
+		},
+	}
+}
+
+// Limits returns an owner's limits: the configured defaults, with a workspace's own limits applied
+func (s *UsageService) Limits(ctx context.Context, owner models.QuotaOwner) (models.QuotaLimits, error) {
+	if owner.Kind != models.OwnerWorkspace {
+		return s.defaults, nil
+	}
+	overrides, err := s.repo.GetQuotaOverrides(ctx, owner.ID)
+	if err != nil {
+		return models.QuotaLimits{}, err
+	}
+	return overrides.Apply(s.defaults), nil
+}
+
+// CountClick meters a click on a link against its owner's monthly quota. It returns
+// ErrClickQuotaExceeded once the quota is used up; anonymous links are never limited.
+func (s *UsageService) CountClick(ctx context.Context, u *models.URL) error {
+	owner, ok := u.QuotaOwner()
+	if !ok {
+		return nil
+	}
+	limits, err := s.Limits(ctx, owner)
+	if err != nil {
+		return err
+	}
+	return s.repo.CountClick(ctx, owner, limits.ClicksPerMonth)
+}
+
+// Usage returns this month's usage against the limits for a workspace the actor can view,
+// or for the actor's personal links when workspaceID is zero
+func (s *UsageService) Usage(ctx context.Context, actor models.Actor, workspaceID int64) (*models.Usage, error) {
+	owner := models.QuotaOwner{Kind: models.OwnerWorkspace, ID: workspaceID}
+	if workspaceID == 0 {
+		if actor.User == nil {
+			return nil, models.ErrSignInRequired
+		}
+		owner = models.QuotaOwner{Kind: models.OwnerUser, ID: actor.User.ID}
+	} else if _, err := s.workspaces.Authorize(ctx, actor, workspaceID, models.PermViewLinks); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.repo.GetUsage(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Limits, err = s.Limits(ctx, owner); err != nil {
+		return nil, err
+	}
+	usage.ResetsAt = NextQuotaPeriod(usage.Period)
+	return usage, nil
+}
+
+// SetWorkspaceQuota gives a workspace its own limits
+func (s *UsageService) SetWorkspaceQuota(ctx context.Context, workspaceID int64, overrides *models.QuotaOverrides) error {
+	for _, limit := range []*int64{overrides.LinksPerMonth, overrides.ActiveLinks, overrides.ClicksPerMonth} {
+		if limit != nil && *limit < 0 {
+			return models.ErrInvalidQuota
+		}
+	}
+	return s.repo.SetQuotaOverrides(ctx, workspaceID, overrides)
+}
+
+// NextQuotaPeriod returns when the monthly counters after period start again. A zero period means the current month.
+func NextQuotaPeriod(period time.Time) time.Time {
+	if period.IsZero() {
+		period = time.Now().UTC()
+	}
+	return time.Date(period.Year(), period.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/auth"
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// Password length limits; the upper one bounds the work a single login can cause
+const (
+	minPasswordLength = 10
+	maxPasswordLength = 128
+)
+
+// UserRepository defines the interface for user and session storage
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	FindUserByID(ctx context.Context, id int64) (*models.User, error)
+	CreateSession(ctx context.Context, session *models.Session) error
+	FindSession(ctx context.Context, tokenHash string) (*models.Session, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+	FindUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	LinkIdentity(ctx context.Context, id int64, issuer, subject string) error
+	UpdateUserProfile(ctx context.Context, id int64, email string, role models.Role) error
+}
+
+// IdentityVerifier verifies JWTs issued by an OpenID Connect provider
+type IdentityVerifier interface {
+	VerifyBearer(ctx context.Context, raw string) (*models.Identity, error)
+}
+
+// UserService handles sign-up, sign-in and sessions
+type UserService struct {
+	repo                UserRepository
+	identities          IdentityVerifier
+	sessionTTL          time.Duration
+	registrationEnabled bool
+	// dummyHash is verified against when an email is unknown, so a login takes as long
+	// whether or not the account exists
+	dummyHash string
+}
+
+// NewUserService creates a new UserService. identities verifies single sign-on bearer
+// tokens and may be nil when single sign-on is off.
+func NewUserService(repo UserRepository, identities IdentityVerifier, cfg *config.Config) (*UserService, error) {
+	dummyHash, err := auth.HashPassword("not a real password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserService{
+		repo:                repo,
+		identities:          identities,
+		sessionTTL:          cfg.SessionTTL,
+		registrationEnabled: cfg.RegistrationEnabled,
+		dummyHash:           dummyHash,
+	}, nil
+}
+
+// RegistrationEnabled reports whether new accounts can be created
+func (s *UserService) RegistrationEnabled() bool {
+	return s.registrationEnabled
+}
+
+// Register creates an account and signs it in, returning the new session's token
+func (s *UserService) Register(ctx context.Context, creds models.Credentials) (string, *models.Session, error) {
+	if !s.registrationEnabled {
+		return "", nil, models.ErrRegistrationClosed
+	}
+
+	email, err := normalizeEmail(creds.Email)
+	if err != nil {
+		return "", nil, err
+	}
+	if n := utf8.RuneCountInString(creds.Password); n < minPasswordLength || n > maxPasswordLength {
+		return "", nil, models.ErrWeakPassword
+	}
+
+	hash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		return "", nil, err
+	}
+	user := &models.User{Email: email, PasswordHash: hash}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return "", nil, err
+	}
+
+	return s.newSession(ctx, user.ID)
+}
+
+// Login checks a user's credentials and starts a session, returning its token
+func (s *UserService) Login(ctx context.Context, creds models.Credentials) (string, *models.Session, error) {
+	if len(creds.Password) > maxPasswordLength*utf8.UTFMax {
+		return "", nil, models.ErrInvalidCredentials
+	}
+
+	user, err := s.repo.FindUserByEmail(ctx, strings.TrimSpace(creds.Email))
+	if err == nil && user.PasswordHash == "" {
+		// Single sign-on accounts can't sign in with a password
+		err = models.ErrUserNotFound
+	}
+	if err == models.ErrUserNotFound {
+		auth.VerifyPassword(creds.Password, s.dummyHash)
+		return "", nil, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	ok, err := auth.VerifyPassword(creds.Password, user.PasswordHash)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, models.ErrInvalidCredentials
+	}
+
+	return s.newSession(ctx, user.ID)
+}
+
+// LoginIdentity signs in the user an identity provider vouched for, creating their account on first login
+func (s *UserService) LoginIdentity(ctx context.Context, identity *models.Identity) (string, *models.Session, error) {
+	user, err := s.resolveIdentity(ctx, identity)
+	if err != nil {
+		return "", nil, err
+	}
+	return s.newSession(ctx, user.ID)
+}
+
+// AuthenticateBearer returns the user a single sign-on JWT was issued to
+func (s *UserService) AuthenticateBearer(ctx context.Context, raw string) (*models.User, error) {
+	if s.identities == nil {
+		return nil, models.ErrInvalidCredentials
+	}
+	identity, err := s.identities.VerifyBearer(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveIdentity(ctx, identity)
+}
+
+// resolveIdentity finds or creates the user for an identity and brings their role, and their
+// email once the provider has verified it, up to date. A passwordless account with the same
+// verified email is linked rather than duplicated; one with a password is refused, since
+// registering doesn't prove the address and its password would keep opening the account.
+func (s *UserService) resolveIdentity(ctx context.Context, identity *models.Identity) (*models.User, error) {
+	user, err := s.repo.FindUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		email, role := user.Email, user.Role
+		if identity.Email != "" && identity.EmailVerified {
+			email = identity.Email
+		}
+		if identity.Role != "" {
+			role = identity.Role
+		}
+		if email != user.Email || role != user.Role {
+			if err := s.repo.UpdateUserProfile(ctx, user.ID, email, role); err != nil {
+				return nil, err
+			}
+			user.Email, user.Role = email, role
+		}
+		return user, nil
+	}
+	if err != models.ErrUserNotFound {
+		return nil, err
+	}
+	if identity.Role == "" {
+		return nil, models.ErrIdentityUnknown
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		return nil, models.ErrIdentityIncomplete
+	}
+
+	if identity.EmailVerified {
+		existing, err := s.repo.FindUserByEmail(ctx, email)
+		if err == nil {
+			if existing.PasswordHash != "" {
+				return nil, models.ErrIdentityConflict
+			}
+			if err := s.repo.LinkIdentity(ctx, existing.ID, identity.Issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+			if err := s.repo.UpdateUserProfile(ctx, existing.ID, existing.Email, identity.Role); err != nil {
+				return nil, err
+			}
+			existing.Role = identity.Role
+			return existing, nil
+		}
+		if err != models.ErrUserNotFound {
+			return nil, err
+		}
+	}
+
+	user = &models.User{
+		Email:       email,
+		Role:        identity.Role,
+		OIDCIssuer:  &identity.Issuer,
+		OIDCSubject: &identity.Subject,
+	}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		if err == models.ErrDuplicateEmail {
+			return nil, models.ErrIdentityConflict
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// newSession starts a session for a user and returns the token to put in its cookie
+func (s *UserService) newSession(ctx context.Context, userID int64) (string, *models.Session, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return "", nil, err
+	}
+	csrfToken, err := auth.NewToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := &models.Session{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		CSRFToken: csrfToken,
+		ExpiresAt: time.Now().Add(s.sessionTTL),
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Authenticate looks up the session and user for a session cookie's token
+func (s *UserService) Authenticate(ctx context.Context, token string) (*models.Session, *models.User, error) {
+	session, err := s.repo.FindSession(ctx, auth.HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.repo.FindUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, user, nil
+}
+
+// Logout ends the session with the given token
+func (s *UserService) Logout(ctx context.Context, token string) error {
+	return s.repo.DeleteSession(ctx, auth.HashToken(token))
+}
+
+// RunSessionCleanup removes expired sessions every interval until ctx is canceled
+func (s *UserService) RunSessionCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n, err := s.repo.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+			log.Printf("Failed to delete expired sessions: %v", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d expired sessions", n)
+		}
+	}
+}
+
+// normalizeEmail validates a bare email address and trims surrounding space
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", models.ErrInvalidEmail
+	}
+	return email, nil
+}
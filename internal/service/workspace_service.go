@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/auth"
+	"github.com/rakheshkrishna2005/url-shortener/internal/config"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// maxWorkspaceNameLength bounds workspace names
+const maxWorkspaceNameLength = 100
+
+// WorkspaceRepository defines the interface for workspace storage
+type WorkspaceRepository interface {
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID int64) error
+	ListWorkspacesForUser(ctx context.Context, userID int64) ([]*models.Workspace, error)
+	MemberRole(ctx context.Context, workspaceID, userID int64) (models.WorkspaceRole, error)
+	ListMembers(ctx context.Context, workspaceID int64) ([]*models.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, workspaceID, userID int64, role models.WorkspaceRole) error
+	RemoveMember(ctx context.Context, workspaceID, userID int64) error
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	ListInvitations(ctx context.Context, workspaceID int64) ([]*models.Invitation, error)
+	DeleteInvitation(ctx context.Context, workspaceID, id int64) error
+	AcceptInvitation(ctx context.Context, tokenHash string, user *models.User) (int64, error)
+}
+
+// WorkspaceService manages workspaces, their members and invitations, and answers
+// permission checks for the links they own
+type WorkspaceService struct {
+	repo          WorkspaceRepository
+	invitationTTL time.Duration
+	baseURL       string
+}
+
+// NewWorkspaceService creates a new WorkspaceService
+func NewWorkspaceService(repo WorkspaceRepository, cfg *config.Config) *WorkspaceService {
+	return &WorkspaceService{
+		repo:          repo,
+		invitationTTL: cfg.InvitationTTL,
+		baseURL:       strings.TrimSuffix(cfg.BaseURL, "/"),
+	}
+}
+
+// Authorize returns the actor's role in a workspace if it grants the permission. Actors that
+// aren't members get ErrWorkspaceNotFound, so workspaces stay invisible to outsiders; members
+// without the permission get ErrForbidden. Admins are allowed everything.
+func (s *WorkspaceService) Authorize(ctx context.Context, actor models.Actor, workspaceID int64, perm models.Permission) (models.WorkspaceRole, error) {
+	if actor.Admin {
+		return models.WorkspaceOwner, nil
+	}
+	if actor.User == nil {
+		return "", models.ErrSignInRequired
+	}
+
+	role, err := s.repo.MemberRole(ctx, workspaceID, actor.User.ID)
+	if err == models.ErrMemberNotFound {
+		return "", models.ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !role.Can(perm) {
+		return role, models.ErrForbidden
+	}
+	return role, nil
+}
+
+// CreateWorkspace creates a workspace owned by the actor
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, actor models.Actor, req models.CreateWorkspaceRequest) (*models.Workspace, error) {
+	if actor.User == nil {
+		return nil, models.ErrSignInRequired
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxWorkspaceNameLength {
+		return nil, models.ErrInvalidWorkspaceName
+	}
+
+	workspace := &models.Workspace{Name: name}
+	if err := s.repo.CreateWorkspace(ctx, workspace, actor.User.ID); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// ListWorkspaces returns the workspaces the actor belongs to
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context, actor models.Actor) ([]*models.Workspace, error) {
+	if actor.User == nil {
+		return nil, models.ErrSignInRequired
+	}
+	return s.repo.ListWorkspacesForUser(ctx, actor.User.ID)
+}
+
+// ListMembers returns a workspace's members; any member may see them
+func (s *WorkspaceService) ListMembers(ctx context.Context, actor models.Actor, workspaceID int64) ([]*models.WorkspaceMember, error) {
+	if _, err := s.Authorize(ctx, actor, workspaceID, models.PermViewLinks); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// SetMemberRole changes a member's role. Both the member's current and new role must be
+// ones the actor may assign.
+func (s *WorkspaceService) SetMemberRole(ctx context.Context, actor models.Actor, workspaceID, userID int64, role models.WorkspaceRole) error {
+	if !role.Valid() {
+		return models.ErrInvalidWorkspaceRole
+	}
+	if err := s.checkManage(ctx, actor, workspaceID, userID, role); err != nil {
+		return err
+	}
+	return s.repo.SetMemberRole(ctx, workspaceID, userID, role)
+}
+
+// RemoveMember takes a member out of a workspace. Members may always leave themselves.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, actor models.Actor, workspaceID, userID int64) error {
+	if actor.User != nil && actor.User.ID == userID {
+		if _, err := s.Authorize(ctx, actor, workspaceID, models.PermViewLinks); err != nil {
+			return err
+		}
+	} else if err := s.checkManage(ctx, actor, workspaceID, userID, ""); err != nil {
+		return err
+	}
+	return s.repo.RemoveMember(ctx, workspaceID, userID)
+}
+
+// checkManage checks that the actor may change the member's current role, and assign newRole if set
+func (s *WorkspaceService) checkManage(ctx context.Context, actor models.Actor, workspaceID, userID int64, newRole models.WorkspaceRole) error {
+	actorRole, err := s.Authorize(ctx, actor, workspaceID, models.PermManageMembers)
+	if err != nil {
+		return err
+	}
+	current, err := s.repo.MemberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !actorRole.CanAssign(current) || (newRole != "" && !actorRole.CanAssign(newRole)) {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// Invite creates an invitation to a workspace. The invitee accepts it with the returned token.
+func (s *WorkspaceService) Invite(ctx context.Context, actor models.Actor, workspaceID int64, req models.InviteRequest) (*models.InvitationResponse, error) {
+	if !req.Role.Valid() {
+		return nil, models.ErrInvalidWorkspaceRole
+	}
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	actorRole, err := s.Authorize(ctx, actor, workspaceID, models.PermManageMembers)
+	if err != nil {
+		return nil, err
+	}
+	if !actorRole.CanAssign(req.Role) {
+		return nil, models.ErrForbidden
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        req.Role,
+		TokenHash:   auth.HashToken(token),
+		InvitedBy:   actor.UserID(),
+		ExpiresAt:   time.Now().Add(s.invitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	return &models.InvitationResponse{
+		Invitation: invitation,
+		Token:      token,
+		AcceptURL:  s.baseURL + "/dashboard?invitation=" + token,
+	}, nil
+}
+
+// ListInvitations returns a workspace's pending invitations
+func (s *WorkspaceService) ListInvitations(ctx context.Context, actor models.Actor, workspaceID int64) ([]*models.Invitation, error) {
+	if _, err := s.Authorize(ctx, actor, workspaceID, models.PermManageMembers); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvitations(ctx, workspaceID)
+}
+
+// RevokeInvitation deletes a pending invitation
+func (s *WorkspaceService) RevokeInvitation(ctx context.Context, actor models.Actor, workspaceID, id int64) error {
+	if _, err := s.Authorize(ctx, actor, workspaceID, models.PermManageMembers); err != nil {
+		return err
+	}
+	return s.repo.DeleteInvitation(ctx, workspaceID, id)
+}
+
+// AcceptInvitation joins the actor to the workspace an invitation is for and returns its ID
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, actor models.Actor, token string) (int64, error) {
+	if actor.User == nil {
+		return 0, models.ErrSignInRequired
+	}
+	if token == "" {
+		return 0, models.ErrInvitationNotFound
+	}
+	return s.repo.AcceptInvitation(ctx, auth.HashToken(token), actor.User)
+}
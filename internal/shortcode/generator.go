@@ -0,0 +1,176 @@
+package shortcode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+// MaxLength is the longest code the urls.short_code column can hold
+const MaxLength = 10
+
+// Strategy names accepted in configuration
+const (
+	StrategyRandom  = "random"
+	StrategyCounter = "counter"
+	StrategyBlock   = "block"
+)
+
+// Generator produces candidate short codes. Uniqueness is enforced by the database;
+// callers retry with a fresh candidate when a code turns out to be taken and
+// report every attempt through Record.
+type Generator interface {
+	Generate(ctx context.Context) (string, error)
+	Record(code string, collided bool)
+	UpdateOccupancy(counts map[int]int64)
+	Stats() Stats
+}
+
+// CounterStore hands out ranges of a shared, monotonically increasing counter
+type CounterStore interface {
+	// ReserveCodeIDs reserves n consecutive values and returns the first one
+	ReserveCodeIDs(ctx context.Context, n int64) (int64, error)
+}
+
+// Options configures a Generator
+type Options struct {
+	// Alphabet defaults to Base62
+	Alphabet  *utils.Alphabet
+	Length    int
+	Secret    string
+	BlockSize int64
+	// GrowCollisionRate and GrowOccupancy move random codes to the next length
+	// once the recent collision rate or the keyspace occupancy reach them
+	GrowCollisionRate float64
+	GrowOccupancy     float64
+}
+
+// New creates the Generator for a configured strategy
+func New(strategy string, opts Options, store CounterStore) (Generator, error) {
+	alphabet := opts.Alphabet
+	if alphabet == nil {
+		alphabet = utils.Base62Alphabet
+	}
+
+	switch strategy {
+	case "", StrategyRandom:
+		return NewRandomGenerator(alphabet, opts.Length, opts.GrowCollisionRate, opts.GrowOccupancy), nil
+	case StrategyCounter:
+		return NewCounterGenerator(store, alphabet, opts.Length, opts.Secret), nil
+	case StrategyBlock:
+		return NewBlockGenerator(store, alphabet, opts.Length, opts.Secret, opts.BlockSize), nil
+	}
+	return nil, fmt.Errorf("unknown short code strategy %q", strategy)
+}
+
+// RandomGenerator picks codes uniformly at random, moving to longer codes as the current length fills up
+type RandomGenerator struct {
+	*Tracker
+	alphabet *utils.Alphabet
+}
+
+// NewRandomGenerator creates a RandomGenerator starting at the given length
+func NewRandomGenerator(alphabet *utils.Alphabet, length int, growCollisionRate, growOccupancy float64) *RandomGenerator {
+	return &RandomGenerator{
+		Tracker:  newTracker(StrategyRandom, alphabet, length, true, growCollisionRate, growOccupancy),
+		alphabet: alphabet,
+	}
+}
+
+// Generate returns a random code of the current length
+func (g *RandomGenerator) Generate(ctx context.Context) (string, error) {
+	return g.alphabet.Generate(g.Length())
+}
+
+// CounterGenerator encodes the next counter value through a keyed permutation,
+// so codes are collision-free among themselves without looking sequential
+type CounterGenerator struct {
+	*Tracker
+	store     CounterStore
+	perm      *Permutation
+	minLength int
+}
+
+// NewCounterGenerator creates a CounterGenerator whose codes are at least minLength long
+func NewCounterGenerator(store CounterStore, alphabet *utils.Alphabet, minLength int, secret string) *CounterGenerator {
+	return &CounterGenerator{
+		Tracker:   newTracker(StrategyCounter, alphabet, minLength, false, 0, 0),
+		store:     store,
+		perm:      NewPermutation(alphabet.Charset, secret),
+		minLength: minLength,
+	}
+}
+
+// Generate reserves the next counter value and encodes it
+func (g *CounterGenerator) Generate(ctx context.Context) (string, error) {
+	n, err := g.store.ReserveCodeIDs(ctx, 1)
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve code id: %w", err)
+	}
+	return encodeID(g.perm, uint64(n), g.minLength)
+}
+
+// BlockGenerator reserves a block of counter values at a time, so each replica
+// hands out codes from memory and only touches the database once per block
+type BlockGenerator struct {
+	*Tracker
+	store     CounterStore
+	perm      *Permutation
+	minLength int
+	blockSize int64
+
+	mu   sync.Mutex
+	next int64
+	end  int64
+}
+
+// NewBlockGenerator creates a BlockGenerator reserving blockSize values at a time
+func NewBlockGenerator(store CounterStore, alphabet *utils.Alphabet, minLength int, secret string, blockSize int64) *BlockGenerator {
+	if blockSize <= 0 {
+		blockSize = 1000
+	}
+	return &BlockGenerator{
+		Tracker:   newTracker(StrategyBlock, alphabet, minLength, false, 0, 0),
+		store:     store,
+		perm:      NewPermutation(alphabet.Charset, secret),
+		minLength: minLength,
+		blockSize: blockSize,
+	}
+}
+
+// Generate encodes the next value of the current block, reserving a new block when it runs out
+func (g *BlockGenerator) Generate(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.next >= g.end {
+		start, err := g.store.ReserveCodeIDs(ctx, g.blockSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to reserve code block: %w", err)
+		}
+		g.next, g.end = start, start+g.blockSize
+	}
+
+	n := g.next
+	g.next++
+	return encodeID(g.perm, uint64(n), g.minLength)
+}
+
+// encodeID maps a counter value to a code. Values are laid out length by length:
+// the first 62^minLength values become codes of minLength, the next 62^(minLength+1)
+// become codes one character longer, and so on.
+func encodeID(perm *Permutation, n uint64, minLength int) (string, error) {
+	for length := minLength; length <= MaxLength; length++ {
+		size := perm.keyspace(length)
+		if size == 0 {
+			break
+		}
+		if n < size {
+			return perm.Encode(n, length)
+		}
+		n -= size
+	}
+	return "", ErrOutOfRange
+}
@@ -0,0 +1,146 @@
+package shortcode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"strings"
+)
+
+// feistelRounds is enough rounds for the output to look unrelated to the input
+const feistelRounds = 4
+
+// ErrOutOfRange is returned when a value doesn't fit the keyspace of a code length
+var ErrOutOfRange = errors.New("value out of range for code length")
+
+// Permutation is a keyed, reversible shuffle of [0, 62^length) used to turn
+// sequential IDs into codes that don't reveal their order
+type Permutation struct {
+	charset string
+	key     []byte
+}
+
+// NewPermutation creates a Permutation over charset keyed with secret
+func NewPermutation(charset, secret string) *Permutation {
+	return &Permutation{
+		charset: charset,
+		key:     []byte(secret),
+	}
+}
+
+// Encode maps n to a code of exactly length characters
+func (p *Permutation) Encode(n uint64, length int) (string, error) {
+	size := p.keyspace(length)
+	if size == 0 || n >= size {
+		return "", ErrOutOfRange
+	}
+
+	v := p.walk(n, size, p.encrypt)
+	return p.format(v, length), nil
+}
+
+// Decode reverses Encode, returning the number a code was generated from
+func (p *Permutation) Decode(code string) (uint64, error) {
+	size := p.keyspace(len(code))
+	if size == 0 {
+		return 0, ErrOutOfRange
+	}
+
+	base := uint64(len(p.charset))
+	var v uint64
+	for _, c := range code {
+		idx := strings.IndexRune(p.charset, c)
+		if idx < 0 {
+			return 0, ErrOutOfRange
+		}
+		v = v*base + uint64(idx)
+	}
+
+	return p.walk(v, size, p.decrypt), nil
+}
+
+// keyspace returns the number of codes of a given length, or 0 when it overflows
+func (p *Permutation) keyspace(length int) uint64 {
+	return keyspace(uint64(len(p.charset)), length)
+}
+
+// keyspace returns base^length, or 0 when length is invalid or the result overflows
+func keyspace(base uint64, length int) uint64 {
+	if length <= 0 {
+		return 0
+	}
+	size := uint64(1)
+	for i := 0; i < length; i++ {
+		hi, lo := bits.Mul64(size, base)
+		if hi != 0 || lo >= 1<<62 {
+			return 0
+		}
+		size = lo
+	}
+	return size
+}
+
+// walk applies a permutation of [0, 2^bits) repeatedly until the result lands
+// inside [0, size), which turns it into a permutation of [0, size)
+func (p *Permutation) walk(v, size uint64, round func(v uint64, half uint) uint64) uint64 {
+	half := halfBits(size)
+	v = round(v, half)
+	for v >= size {
+		v = round(v, half)
+	}
+	return v
+}
+
+func (p *Permutation) encrypt(v uint64, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	l, r := v>>half, v&mask
+	for i := 0; i < feistelRounds; i++ {
+		l, r = r, l^(p.round(i, r)&mask)
+	}
+	return l<<half | r
+}
+
+func (p *Permutation) decrypt(v uint64, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	l, r := v>>half, v&mask
+	for i := feistelRounds - 1; i >= 0; i-- {
+		l, r = r^(p.round(i, l)&mask), l
+	}
+	return l<<half | r
+}
+
+// round is the Feistel round function, a keyed hash of the round number and half-block
+func (p *Permutation) round(i int, v uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(i)
+	binary.BigEndian.PutUint64(buf[1:], v)
+
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// format writes v in the charset's base, left-padded to length
+func (p *Permutation) format(v uint64, length int) string {
+	base := uint64(len(p.charset))
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = p.charset[v%base]
+		v /= base
+	}
+	return string(out)
+}
+
+// halfBits returns half the even number of bits needed to represent size-1
+func halfBits(size uint64) uint {
+	n := uint(bits.Len64(size - 1))
+	if n%2 == 1 {
+		n++
+	}
+	if n == 0 {
+		n = 2
+	}
+	return n / 2
+}
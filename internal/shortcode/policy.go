@@ -0,0 +1,141 @@
+package shortcode
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// DefaultBlocklist is used when no blocklist is configured. Words that turn up inside
+// ordinary ones, like "rape" in "grapes" or "cunt" in "scunthorpe", only match whole words;
+// the unambiguous ones are written *word* to match anywhere in a code.
+var DefaultBlocklist = []string{
+	"*bitch*", "boob", "cock", "cunt", "dick", "*dildo*", "fag", "*fuck*", "*jizz*", "kike",
+	"nazi", "*nigg*", "penis", "piss", "*porn*", "*pussy*", "rape", "*shit*", "*slut*", "tits",
+	"twat", "*vagina*", "wank", "*whore*",
+}
+
+// leetReplacer folds look-alike digits and symbols onto letters. Both codes and
+// blocklist words go through it, so "l" and "1" both become "i" on each side.
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"l", "i",
+	"!", "i",
+	"3", "e",
+	"4", "a",
+	"@", "a",
+	"5", "s",
+	"$", "s",
+	"7", "t",
+	"8", "b",
+	"9", "g",
+	"-", "",
+	"_", "",
+	".", "",
+)
+
+// Policy decides which codes and aliases may be used
+type Policy struct {
+	// blocked words match whole words of a code, stems match anywhere in it
+	blocked  map[string]bool
+	stems    []string
+	reserved map[string]bool
+}
+
+// NewPolicy creates a Policy rejecting codes that contain a blocked word or equal a
+// reserved one. Blocklist entries match whole words, split at hyphens, underscores, dots
+// and lower-to-upper case changes; entries written *word* match anywhere. Both
+// comparisons ignore case.
+func NewPolicy(blocklist, reserved []string) *Policy {
+	p := &Policy{blocked: make(map[string]bool), reserved: make(map[string]bool)}
+	for _, entry := range blocklist {
+		entry = strings.TrimSpace(entry)
+		stem := len(entry) > 2 && strings.HasPrefix(entry, "*") && strings.HasSuffix(entry, "*")
+		word := normalizeCode(strings.Trim(entry, "*"))
+		switch {
+		case word == "":
+		case stem:
+			p.stems = append(p.stems, word)
+		default:
+			p.blocked[word] = true
+		}
+	}
+	for _, word := range reserved {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.reserved[word] = true
+		}
+	}
+	return p
+}
+
+// Check returns models.ErrAliasReserved or models.ErrAliasBlocked when code may not be used
+func (p *Policy) Check(code string) error {
+	if p.reserved[strings.ToLower(code)] {
+		return models.ErrAliasReserved
+	}
+
+	normalized := normalizeCode(code)
+	for _, stem := range p.stems {
+		if strings.Contains(normalized, stem) {
+			return models.ErrAliasBlocked
+		}
+	}
+	for _, word := range codeWords(code) {
+		if p.blocked[normalizeCode(word)] {
+			return models.ErrAliasBlocked
+		}
+	}
+	return nil
+}
+
+// codeWords splits a code into words at hyphens, underscores, dots and where a lowercase
+// letter or digit is followed by an uppercase one, as in "myBrand"
+func codeWords(code string) []string {
+	var words []string
+	start := 0
+	for i, r := range code {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+			words = append(words, code[start:i])
+			start = i + 1
+		case i > start && unicode.IsUpper(r) && !unicode.IsUpper(rune(code[i-1])):
+			words = append(words, code[start:i])
+			start = i
+		}
+	}
+	return append(words, code[start:])
+}
+
+// normalizeCode lowercases a code and undoes common leetspeak substitutions
+func normalizeCode(code string) string {
+	return leetReplacer.Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// LoadBlocklist reads one word per line, skipping blank lines and # comments. Words written
+// *word* match anywhere in a code, others only whole words.
+func LoadBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	return words, nil
+}
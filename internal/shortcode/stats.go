@@ -0,0 +1,327 @@
+package shortcode
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/metrics"
+	"github.com/rakheshkrishna2005/url-shortener/internal/utils"
+)
+
+const (
+	// collisionWindow is how many recent attempts the collision rate is measured over
+	collisionWindow = 1000
+	// minCollisionSamples avoids growing on a handful of unlucky attempts
+	minCollisionSamples = 100
+)
+
+// OccupancyStore counts the codes already in use
+type OccupancyStore interface {
+	// CountShortCodesByLength returns how many codes of each length are taken on the
+	// domain that uses the most of them; codes only collide within a domain
+	CountShortCodesByLength(ctx context.Context) (map[int]int64, error)
+}
+
+// Stats is a snapshot of code generation for a strategy
+type Stats struct {
+	Strategy      string        `json:"strategy"`
+	Length        int           `json:"length"`
+	MaxLength     int           `json:"max_length"`
+	CollisionRate float64       `json:"collision_rate"`
+	Lengths       []LengthStats `json:"lengths"`
+}
+
+// LengthStats describes one code length
+type LengthStats struct {
+	Length     int     `json:"length"`
+	Attempts   uint64  `json:"attempts"`
+	Collisions uint64  `json:"collisions"`
+	Occupied   int64   `json:"occupied"`
+	Keyspace   uint64  `json:"keyspace"`
+	Occupancy  float64 `json:"occupancy"`
+}
+
+type lengthCounters struct {
+	attempts   uint64
+	collisions uint64
+	occupied   int64
+}
+
+// Tracker records collisions and occupancy per code length. When adaptive, it
+// moves to the next length once either crosses its threshold.
+type Tracker struct {
+	strategy         string
+	adaptive         bool
+	maxCollisionRate float64
+	maxOccupancy     float64
+	base             uint64
+
+	mu      sync.Mutex
+	length  int
+	lengths map[int]*lengthCounters
+	recent  []bool
+	pos     int
+	filled  int
+	hits    int
+}
+
+// newTracker creates a Tracker starting at length. Thresholds of 0 disable that trigger.
+func newTracker(strategy string, alphabet *utils.Alphabet, length int, adaptive bool, maxCollisionRate, maxOccupancy float64) *Tracker {
+	if length > MaxLength {
+		length = MaxLength
+	}
+	return &Tracker{
+		strategy:         strategy,
+		adaptive:         adaptive,
+		maxCollisionRate: maxCollisionRate,
+		maxOccupancy:     maxOccupancy,
+		base:             uint64(len(alphabet.Charset)),
+		length:           length,
+		lengths:          make(map[int]*lengthCounters),
+		recent:           make([]bool, collisionWindow),
+	}
+}
+
+// Length returns the length new codes are generated at
+func (t *Tracker) Length() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.length
+}
+
+// Record notes whether storing a generated code collided with an existing one
+func (t *Tracker) Record(code string, collided bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(len(code))
+	c.attempts++
+	if collided {
+		c.collisions++
+	} else {
+		c.occupied++
+	}
+
+	if !t.adaptive {
+		// Counter strategies move to longer codes on their own
+		if len(code) > t.length {
+			t.length = len(code)
+		}
+		return
+	}
+	if len(code) != t.length {
+		return
+	}
+
+	if t.recent[t.pos] {
+		t.hits--
+	}
+	t.recent[t.pos] = collided
+	if collided {
+		t.hits++
+	}
+	t.pos = (t.pos + 1) % len(t.recent)
+	if t.filled < len(t.recent) {
+		t.filled++
+	}
+
+	t.grow()
+}
+
+// UpdateOccupancy replaces the occupied counts with fresh totals from the database.
+// An adaptive tracker also moves up to the longest length codes are stored at: that length
+// was reached before, possibly through collisions, which aren't remembered across restarts.
+func (t *Tracker) UpdateOccupancy(counts map[int]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.lengths {
+		c.occupied = 0
+	}
+	for length, n := range counts {
+		if length > 0 && length <= MaxLength {
+			t.counters(length).occupied = n
+		}
+	}
+
+	if t.adaptive {
+		t.resume()
+		t.grow()
+	}
+}
+
+// Stats returns a snapshot of the tracked statistics
+func (t *Tracker) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := Stats{
+		Strategy:      t.strategy,
+		Length:        t.length,
+		MaxLength:     MaxLength,
+		CollisionRate: t.collisionRate(),
+	}
+	for length, c := range t.lengths {
+		stats.Lengths = append(stats.Lengths, LengthStats{
+			Length:     length,
+			Attempts:   c.attempts,
+			Collisions: c.collisions,
+			Occupied:   c.occupied,
+			Keyspace:   keyspace(t.base, length),
+			Occupancy:  t.occupancy(length),
+		})
+	}
+	sort.Slice(stats.Lengths, func(i, j int) bool {
+		return stats.Lengths[i].Length < stats.Lengths[j].Length
+	})
+
+	return stats
+}
+
+// grow moves to longer codes while the current length is saturated. Callers hold t.mu.
+func (t *Tracker) grow() {
+	for t.length < MaxLength {
+		rate := t.collisionRate()
+		occupancy := t.occupancy(t.length)
+
+		byRate := t.maxCollisionRate > 0 && t.filled >= minCollisionSamples && rate >= t.maxCollisionRate
+		byOccupancy := t.maxOccupancy > 0 && occupancy >= t.maxOccupancy
+		if !byRate && !byOccupancy {
+			return
+		}
+
+		log.Printf("Short codes of length %d are saturated (collision rate %.3f, occupancy %.3f); growing to %d",
+			t.length, rate, occupancy, t.length+1)
+		t.length++
+
+		// The recent window describes the old length
+		for i := range t.recent {
+			t.recent[i] = false
+		}
+		t.pos, t.filled, t.hits = 0, 0, 0
+	}
+}
+
+// resume moves to the longest length with stored codes, if it is beyond the current one.
+// Callers hold t.mu.
+func (t *Tracker) resume() {
+	longest := t.length
+	for length, c := range t.lengths {
+		if c.occupied > 0 && length > longest {
+			longest = length
+		}
+	}
+	if longest == t.length {
+		return
+	}
+
+	log.Printf("Short codes of length %d are already in use; resuming at that length instead of %d", longest, t.length)
+	t.length = longest
+	for i := range t.recent {
+		t.recent[i] = false
+	}
+	t.pos, t.filled, t.hits = 0, 0, 0
+}
+
+// collisionRate returns the share of recent attempts that collided. Callers hold t.mu.
+func (t *Tracker) collisionRate() float64 {
+	if t.filled == 0 {
+		return 0
+	}
+	return float64(t.hits) / float64(t.filled)
+}
+
+// occupancy returns the share of the keyspace of a length already in use. Callers hold t.mu.
+func (t *Tracker) occupancy(length int) float64 {
+	size := keyspace(t.base, length)
+	c, ok := t.lengths[length]
+	if size == 0 || !ok {
+		return 0
+	}
+	return float64(c.occupied) / float64(size)
+}
+
+// counters returns the counters for a length, creating them if needed. Callers hold t.mu.
+func (t *Tracker) counters(length int) *lengthCounters {
+	c, ok := t.lengths[length]
+	if !ok {
+		c = &lengthCounters{}
+		t.lengths[length] = c
+	}
+	return c
+}
+
+// LoadOccupancy refreshes a generator's occupancy from the database once. Calling it at startup
+// lets a random generator resume at the length it had grown to before the restart.
+func LoadOccupancy(ctx context.Context, gen Generator, store OccupancyStore) error {
+	counts, err := store.CountShortCodesByLength(ctx)
+	if err != nil {
+		return err
+	}
+	gen.UpdateOccupancy(counts)
+	return nil
+}
+
+// WatchOccupancy refreshes a generator's occupancy from the database every interval until ctx is canceled
+func WatchOccupancy(ctx context.Context, gen Generator, store OccupancyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := LoadOccupancy(ctx, gen, store); err != nil {
+			log.Printf("Failed to count short codes: %v", err)
+		}
+	}
+}
+
+// RegisterMetrics exports a generator's statistics
+func RegisterMetrics(reg *metrics.Registry, gen Generator) {
+	reg.Gauge("ziply_short_code_length", "Length new short codes are generated at.", func() float64 {
+		return float64(gen.Stats().Length)
+	})
+	reg.Gauge("ziply_short_code_collision_rate", "Share of recent generated codes that collided.", func() float64 {
+		return gen.Stats().CollisionRate
+	})
+
+	perLength := func(value func(LengthStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, l := range gen.Stats().Lengths {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"length": strconv.Itoa(l.Length)},
+					Value:  value(l),
+				})
+			}
+			return samples
+		}
+	}
+	reg.Register(metrics.Metric{
+		Name:    "ziply_short_code_attempts_total",
+		Help:    "Generated short codes tried, by length.",
+		Type:    metrics.TypeCounter,
+		Collect: perLength(func(l LengthStats) float64 { return float64(l.Attempts) }),
+	})
+	reg.Register(metrics.Metric{
+		Name:    "ziply_short_code_collisions_total",
+		Help:    "Generated short codes that were already taken, by length.",
+		Type:    metrics.TypeCounter,
+		Collect: perLength(func(l LengthStats) float64 { return float64(l.Collisions) }),
+	})
+	reg.Register(metrics.Metric{
+		Name:    "ziply_short_code_occupancy_ratio",
+		Help:    "Share of the keyspace of each length already in use.",
+		Type:    metrics.TypeGauge,
+		Collect: perLength(func(l LengthStats) float64 { return l.Occupancy }),
+	})
+}
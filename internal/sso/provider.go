@@ -0,0 +1,182 @@
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+	"golang.org/x/oauth2"
+)
+
+// Errors returned when a login or bearer token can't be accepted
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrNonceInvalid = errors.New("id token nonce does not match the login request")
+	ErrNoRole       = errors.New("identity has no role in this deployment")
+)
+
+// Options configures an OpenID Connect provider
+type Options struct {
+	// IssuerURL is where the discovery document is loaded from
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// APIAudience is the audience bearer tokens must be issued for; defaults to ClientID
+	APIAudience string
+	Roles       RoleMapping
+	// HTTPClient talks to the provider; defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// Provider signs users in with an OpenID Connect provider using the authorization code
+// flow with PKCE, and verifies the JWTs it issues. The discovery document is loaded on
+// first use and retried until it succeeds, so a provider outage doesn't stop startup.
+type Provider struct {
+	opts Options
+
+	mu        sync.Mutex
+	provider  *gooidc.Provider
+	oauth2    oauth2.Config
+	idTokens  *gooidc.IDTokenVerifier
+	apiTokens *gooidc.IDTokenVerifier
+}
+
+// New creates a Provider
+func New(opts Options) *Provider {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{"email", "profile"}
+	}
+	if opts.APIAudience == "" {
+		opts.APIAudience = opts.ClientID
+	}
+	return &Provider{opts: opts}
+}
+
+// load fetches the discovery document and sets up the token verifiers, once
+func (p *Provider) load(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return nil
+	}
+
+	provider, err := gooidc.NewProvider(p.clientContext(ctx), p.opts.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("failed to load OIDC discovery document: %w", err)
+	}
+
+	scopes := append([]string{gooidc.ScopeOpenID}, p.opts.Scopes...)
+	p.oauth2 = oauth2.Config{
+		ClientID:     p.opts.ClientID,
+		ClientSecret: p.opts.ClientSecret,
+		RedirectURL:  p.opts.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	p.idTokens = provider.Verifier(&gooidc.Config{ClientID: p.opts.ClientID})
+	p.apiTokens = provider.Verifier(&gooidc.Config{ClientID: p.opts.APIAudience})
+	p.provider = provider
+	return nil
+}
+
+// clientContext makes go-oidc and oauth2 use the provider's HTTP client
+func (p *Provider) clientContext(ctx context.Context) context.Context {
+	return gooidc.ClientContext(ctx, p.opts.HTTPClient)
+}
+
+// AuthCodeURL returns the provider URL to send the browser to. state and nonce tie the
+// callback to this login; verifier is the PKCE code verifier, sent as its S256 challenge.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	if err := p.load(ctx); err != nil {
+		return "", err
+	}
+	return p.oauth2.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code and returns the identity in its verified ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*models.Identity, error) {
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2.Exchange(p.clientContext(ctx), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := p.idTokens.Verify(p.clientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceInvalid
+	}
+
+	return p.identity(idToken, true)
+}
+
+// VerifyBearer verifies a JWT access token issued for the API audience
+func (p *Provider) VerifyBearer(ctx context.Context, raw string) (*models.Identity, error) {
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := p.apiTokens.Verify(p.clientContext(ctx), raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	// Access tokens often leave out the role claim; the user then keeps the role from their last login
+	return p.identity(token, false)
+}
+
+// identity reads the user and their role out of a verified token's claims. Unless requireRole
+// is set, a token without the role claim yields an identity with no role.
+func (p *Provider) identity(token *gooidc.IDToken, requireRole bool) (*models.Identity, error) {
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	identity := &models.Identity{
+		Issuer:  token.Issuer,
+		Subject: token.Subject,
+	}
+	if requireRole || p.opts.Roles.Present(claims) {
+		role, ok := p.opts.Roles.Role(claims)
+		if !ok {
+			return nil, ErrNoRole
+		}
+		identity.Role = role
+	}
+	identity.Email, _ = claims["email"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		// Some providers send the flag as a string
+		identity.EmailVerified = strings.EqualFold(verified, "true")
+	}
+	return identity, nil
+}
+
+// LooksLikeJWT reports whether a bearer token has the three dot-separated parts of a JWT,
+// telling provider tokens apart from opaque ones such as the admin token
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,233 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// testIssuer is an OpenID Connect provider serving its discovery document, signing keys and
+// token endpoint from an httptest server
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// idToken is returned by the token endpoint
+	idToken string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	iss := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                iss.URL,
+			"authorization_endpoint":                iss.URL + "/authorize",
+			"token_endpoint":                        iss.URL + "/token",
+			"jwks_uri":                              iss.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test-key", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "opaque-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     iss.idToken,
+		})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// claims returns a valid set of claims for audience, which the test may then change
+func (iss *testIssuer) claims(audience string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            iss.URL,
+		"sub":            "user-123",
+		"aud":            audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "someone@example.com",
+		"email_verified": true,
+		"groups":         []string{"staff"},
+	}
+}
+
+// sign returns claims as a JWT signed with key
+func (iss *testIssuer) sign(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test-key"),
+	)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	raw, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	return raw
+}
+
+func newTestProvider(iss *testIssuer) *Provider {
+	return New(Options{
+		IssuerURL:   iss.URL,
+		ClientID:    "web-client",
+		APIAudience: "api",
+		RedirectURL: "https://short.example/auth/callback",
+		Roles: RoleMapping{
+			Claim:  "groups",
+			Values: map[string]models.Role{"staff": models.RoleUser, "ops": models.RoleAdmin},
+		},
+	})
+}
+
+func TestVerifyBearer(t *testing.T) {
+	iss := newTestIssuer(t)
+	provider := newTestProvider(iss)
+
+	claims := iss.claims("api")
+	claims["groups"] = []string{"staff", "ops"}
+	identity, err := provider.VerifyBearer(context.Background(), iss.sign(t, iss.key, claims))
+	if err != nil {
+		t.Fatalf("VerifyBearer: %v", err)
+	}
+
+	want := models.Identity{
+		Issuer:        iss.URL,
+		Subject:       "user-123",
+		Email:         "someone@example.com",
+		EmailVerified: true,
+		Role:          models.RoleAdmin,
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestVerifyBearerWithoutRoleClaim(t *testing.T) {
+	iss := newTestIssuer(t)
+	provider := newTestProvider(iss)
+
+	claims := iss.claims("api")
+	delete(claims, "groups")
+	identity, err := provider.VerifyBearer(context.Background(), iss.sign(t, iss.key, claims))
+	if err != nil {
+		t.Fatalf("VerifyBearer: %v", err)
+	}
+	if identity.Role != "" {
+		t.Errorf("role = %q, want none so the user keeps their last one", identity.Role)
+	}
+}
+
+func TestVerifyBearerRejectsInvalidTokens(t *testing.T) {
+	iss := newTestIssuer(t)
+	provider := newTestProvider(iss)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		modify func(claims map[string]interface{})
+	}{
+		{"expired", iss.key, func(c map[string]interface{}) {
+			c["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}},
+		{"wrong audience", iss.key, func(c map[string]interface{}) { c["aud"] = "web-client" }},
+		{"wrong issuer", iss.key, func(c map[string]interface{}) { c["iss"] = "https://issuer.example" }},
+		{"bad signature", otherKey, func(c map[string]interface{}) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := iss.claims("api")
+			tt.modify(claims)
+
+			_, err := provider.VerifyBearer(context.Background(), iss.sign(t, tt.key, claims))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("VerifyBearer error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	iss := newTestIssuer(t)
+	provider := newTestProvider(iss)
+
+	tests := []struct {
+		name    string
+		nonce   string
+		groups  []string
+		wantErr error
+	}{
+		{"valid", "nonce-1", []string{"staff"}, nil},
+		{"nonce mismatch", "nonce-2", []string{"staff"}, ErrNonceInvalid},
+		{"no role", "nonce-1", []string{"visitors"}, ErrNoRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := iss.claims("web-client")
+			claims["nonce"] = "nonce-1"
+			claims["groups"] = tt.groups
+			iss.idToken = iss.sign(t, iss.key, claims)
+
+			identity, err := provider.Exchange(context.Background(), "code", "verifier", tt.nonce)
+			if err != tt.wantErr {
+				t.Fatalf("Exchange error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && identity.Role != models.RoleUser {
+				t.Errorf("role = %q, want %q", identity.Role, models.RoleUser)
+			}
+		})
+	}
+}
+
+func TestLooksLikeJWT(t *testing.T) {
+	tests := map[string]bool{
+		"header.payload.signature": true,
+		"an-opaque-admin-token":    false,
+		"two.parts":                false,
+	}
+	for token, want := range tests {
+		if got := LooksLikeJWT(token); got != want {
+			t.Errorf("LooksLikeJWT(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sso
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rakheshkrishna2005/url-shortener/internal/models"
+)
+
+// RoleMapping turns the values of a token claim, such as group names, into a role
+type RoleMapping struct {
+	// Claim is the claim holding the values, e.g. "groups"; unless a claim has that exact
+	// name, dots reach into nested objects, e.g. "realm_access.roles"
+	Claim string
+	// Values maps claim values to roles; the most privileged match wins
+	Values map[string]models.Role
+	// Default is the role of users no value matches. Empty refuses them.
+	Default models.Role
+}
+
+// ParseRoleMap parses "value=role" pairs, e.g. ["ziply-admins=admin", "staff=user"]
+func ParseRoleMap(pairs []string) (map[string]models.Role, error) {
+	values := make(map[string]models.Role, len(pairs))
+	for _, pair := range pairs {
+		value, role, ok := strings.Cut(pair, "=")
+		if !ok || value == "" || !models.Role(role).Valid() {
+			return nil, fmt.Errorf("invalid role mapping %q: want value=user or value=admin", pair)
+		}
+		values[value] = models.Role(role)
+	}
+	return values, nil
+}
+
+// Role returns the role the claims map to, and false if they map to none
+func (m RoleMapping) Role(claims map[string]interface{}) (models.Role, bool) {
+	var role models.Role
+	for _, value := range claimValues(claims, m.Claim) {
+		if mapped, ok := m.Values[value]; ok && (role == "" || mapped.Outranks(role)) {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = m.Default
+	}
+	return role, role != ""
+}
+
+// Present reports whether the claims include the role claim at all
+func (m RoleMapping) Present(claims map[string]interface{}) bool {
+	return claimValues(claims, m.Claim) != nil
+}
+
+// claimValues returns the string values of a possibly nested claim that holds a string or a list
+func claimValues(claims map[string]interface{}, path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	// Namespaced claims such as "https://example.com/roles" contain dots themselves
+	value, ok := claims[path]
+	if !ok {
+		value = claims
+		for _, key := range strings.Split(path, ".") {
+			object, isObject := value.(map[string]interface{})
+			if !isObject {
+				return nil
+			}
+			value = object[key]
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
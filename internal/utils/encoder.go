@@ -1,58 +1,131 @@
-package utils
-
-import (
-	"crypto/rand"
-	"math/big"
-	"strings"
-)
-
-const (
-	// Base62Charset contains all characters used for Base62 encoding
-	Base62Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-)
-
-// GenerateShortCode creates a random short code of specified length
-func GenerateShortCode(length int) (string, error) {
-	// Hard limit to 10 characters to match database constraint
-	if length <= 0 || length > 10 {
-		length = 6 // Default safe length
-	}
-
-	charsetLength := big.NewInt(int64(len(Base62Charset)))
-	result := strings.Builder{}
-	result.Grow(length)
-
-	for i := 0; i < length; i++ {
-		randIndex, err := rand.Int(rand.Reader, charsetLength)
-		if err != nil {
-			return "", err
-		}
-		result.WriteByte(Base62Charset[randIndex.Int64()])
-	}
-
-	return result.String(), nil
-}
-
-// IsValidShortCode checks if a short code contains only valid characters
-func IsValidShortCode(code string) bool {
-	for _, c := range code {
-		if !strings.ContainsRune(Base62Charset, c) {
-			return false
-		}
-	}
-	return len(code) > 0
-}
-
-// IsValidCustomAlias checks if a custom alias contains only valid characters
-func IsValidCustomAlias(alias string) bool {
-	if len(alias) < 3 || len(alias) > 10 {
-		return false
-	}
-
-	for _, c := range alias {
-		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')) {
-			return false
-		}
-	}
-	return true
-}
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// AliasPattern matches custom aliases in routes
+const AliasPattern = `[A-Za-z0-9_-]+`
+
+var customAliasRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{3,50}$`)
+
+const (
+	// Base62Charset contains all characters used for Base62 encoding
+	Base62Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// UnambiguousCharset leaves out characters that are easily confused in print: 0, O, 1, l and I
+	UnambiguousCharset = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	// LowercaseCharset has a single case, so codes can be typed in any case
+	LowercaseCharset = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// Alphabet is the set of characters short codes are made of
+type Alphabet struct {
+	Name    string
+	Charset string
+	// CaseInsensitive alphabets only contain lowercase letters; new codes and aliases are
+	// stored lowercased and typed codes also match their lowercase form
+	CaseInsensitive bool
+}
+
+// Built-in alphabets
+var (
+	Base62Alphabet      = &Alphabet{Name: "base62", Charset: Base62Charset}
+	UnambiguousAlphabet = &Alphabet{Name: "unambiguous", Charset: UnambiguousCharset}
+	LowercaseAlphabet   = &Alphabet{Name: "lowercase", Charset: LowercaseCharset, CaseInsensitive: true}
+)
+
+// NewAlphabet returns a built-in alphabet by name, or builds one from a string of
+// alphanumeric characters. Alphabets without uppercase letters are case-insensitive.
+func NewAlphabet(spec string) (*Alphabet, error) {
+	switch spec {
+	case "", Base62Alphabet.Name:
+		return Base62Alphabet, nil
+	case UnambiguousAlphabet.Name:
+		return UnambiguousAlphabet, nil
+	case LowercaseAlphabet.Name:
+		return LowercaseAlphabet, nil
+	}
+
+	if len(spec) < 2 {
+		return nil, fmt.Errorf("alphabet must have at least 2 characters")
+	}
+	seen := make(map[rune]bool)
+	caseInsensitive := true
+	for _, c := range spec {
+		if !isAlphanumeric(c) {
+			return nil, fmt.Errorf("alphabet may only contain ASCII letters and digits, got %q", c)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("alphabet contains %q more than once", c)
+		}
+		seen[c] = true
+		if c >= 'A' && c <= 'Z' {
+			caseInsensitive = false
+		}
+	}
+
+	return &Alphabet{Name: "custom", Charset: spec, CaseInsensitive: caseInsensitive}, nil
+}
+
+// Generate creates a random code of the given length
+func (a *Alphabet) Generate(length int) (string, error) {
+	// Hard limit to 10 characters to match database constraint
+	if length <= 0 || length > 10 {
+		length = 6 // Default safe length
+	}
+
+	charsetLength := big.NewInt(int64(len(a.Charset)))
+	result := strings.Builder{}
+	result.Grow(length)
+
+	for i := 0; i < length; i++ {
+		randIndex, err := rand.Int(rand.Reader, charsetLength)
+		if err != nil {
+			return "", err
+		}
+		result.WriteByte(a.Charset[randIndex.Int64()])
+	}
+
+	return result.String(), nil
+}
+
+// Normalize returns the stored form of a code typed by a user
+func (a *Alphabet) Normalize(code string) string {
+	if a.CaseInsensitive {
+		return strings.ToLower(code)
+	}
+	return code
+}
+
+// Variants returns the stored forms a typed code may have, the code as typed first. Stored
+// codes keep the case they were created with, so after a switch to a case-insensitive
+// alphabet, links made under the earlier one still resolve by their exact code.
+func (a *Alphabet) Variants(code string) []string {
+	if normalized := a.Normalize(code); normalized != code {
+		return []string{code, normalized}
+	}
+	return []string{code}
+}
+
+// IsValid checks if a code only contains characters of the alphabet, after normalization
+func (a *Alphabet) IsValid(code string) bool {
+	for _, c := range a.Normalize(code) {
+		if !strings.ContainsRune(a.Charset, c) {
+			return false
+		}
+	}
+	return len(code) > 0
+}
+
+// IsValidCustomAlias checks if a custom alias is 3-50 letters, digits, hyphens or underscores
+func IsValidCustomAlias(alias string) bool {
+	return customAliasRegex.MatchString(alias)
+}
+
+func isAlphanumeric(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
@@ -0,0 +1,33 @@
+package version
+
+import "runtime/debug"
+
+// Set at build time with -ldflags "-X github.com/rakheshkrishna2005/url-shortener/internal/version.Version=..."
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+}
+
+// Get returns the build version and git commit, falling back to the VCS stamp Go embeds in the binary
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit}
+	if info.Commit != "" {
+		return info
+	}
+
+	info.Commit = "unknown"
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	return info
+}
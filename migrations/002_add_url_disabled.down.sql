@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,3 @@
+-- Track links disabled by the destination policy instead of deleting them
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
@@ -0,0 +1,12 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
+
+UPDATE urls
+SET disabled_at = COALESCE(status_changed_at, NOW()), disabled_reason = status_reason
+WHERE status <> 'active';
+
+DROP INDEX IF EXISTS idx_urls_status;
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_status_check;
+ALTER TABLE urls DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE urls DROP COLUMN IF EXISTS status;
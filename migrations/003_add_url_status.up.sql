@@ -0,0 +1,18 @@
+-- Replace the policy-only disabled flag with a general moderation status
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS status_reason TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE urls ADD CONSTRAINT urls_status_check
+    CHECK (status IN ('active', 'disabled', 'flagged', 'pending_review'));
+
+-- Links disabled by the destination policy become flagged
+UPDATE urls
+SET status = 'flagged', status_reason = disabled_reason, status_changed_at = disabled_at
+WHERE disabled_at IS NOT NULL;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled_at;
+
+-- Create index on status for moderation queues
+CREATE INDEX IF NOT EXISTS idx_urls_status ON urls(status) WHERE status <> 'active';
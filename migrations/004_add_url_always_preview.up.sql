@@ -0,0 +1,2 @@
+-- Links that always show the preview interstitial before redirecting
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS always_preview BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,15 @@
+-- Create metadata table for scraped destination details
+CREATE TABLE IF NOT EXISTS url_metadata (
+    url_id INTEGER PRIMARY KEY REFERENCES urls(id) ON DELETE CASCADE,
+    source_url TEXT NOT NULL,
+    title TEXT,
+    description TEXT,
+    og_title TEXT,
+    og_description TEXT,
+    og_site_name TEXT,
+    og_image_url TEXT,
+    favicon_url TEXT,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    fetch_error TEXT,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_health_failures;
+DROP INDEX IF EXISTS idx_url_health_last_checked_at;
+DROP TABLE IF EXISTS url_health;
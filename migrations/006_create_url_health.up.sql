@@ -0,0 +1,17 @@
+-- Create health table for periodic destination checks
+CREATE TABLE IF NOT EXISTS url_health (
+    url_id INTEGER PRIMARY KEY REFERENCES urls(id) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    final_url TEXT,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_checked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_success_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create index on last_checked_at for scheduling checks
+CREATE INDEX IF NOT EXISTS idx_url_health_last_checked_at ON url_health(last_checked_at);
+
+-- Create index on consecutive_failures for the broken links listing
+CREATE INDEX IF NOT EXISTS idx_url_health_failures ON url_health(consecutive_failures) WHERE consecutive_failures > 0;
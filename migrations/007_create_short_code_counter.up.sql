@@ -0,0 +1,7 @@
+-- Create single-row counter for sequence-based short code generation
+CREATE TABLE IF NOT EXISTS short_code_counter (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    next_value BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO short_code_counter (id, next_value) VALUES (TRUE, 0) ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,15 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS custom_alias VARCHAR(50) UNIQUE;
+
+-- Keep each link's oldest active alias
+UPDATE urls
+SET custom_alias = (
+    SELECT alias FROM url_aliases
+    WHERE url_aliases.url_id = urls.id AND url_aliases.retired_at IS NULL
+    ORDER BY created_at, alias
+    LIMIT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_urls_custom_alias ON urls(custom_alias);
+
+DROP INDEX IF EXISTS idx_url_aliases_url_id;
+DROP TABLE IF EXISTS url_aliases;
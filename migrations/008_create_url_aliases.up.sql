@@ -0,0 +1,21 @@
+-- Create aliases table so a link can answer on several names
+CREATE TABLE IF NOT EXISTS url_aliases (
+    alias VARCHAR(50) PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP WITH TIME ZONE,
+    redirect_until TIMESTAMP WITH TIME ZONE
+);
+
+-- Create index on url_id for listing a link's aliases
+CREATE INDEX IF NOT EXISTS idx_url_aliases_url_id ON url_aliases(url_id);
+
+-- Move existing custom aliases over
+INSERT INTO url_aliases (alias, url_id, created_at)
+SELECT custom_alias, id, COALESCE(created_at, NOW())
+FROM urls
+WHERE custom_alias IS NOT NULL
+ON CONFLICT (alias) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_urls_custom_alias;
+ALTER TABLE urls DROP COLUMN IF EXISTS custom_alias;
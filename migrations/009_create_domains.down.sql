@@ -0,0 +1,12 @@
+-- Fails if the same code or alias is in use on more than one domain
+DROP INDEX IF EXISTS url_aliases_domain_alias_key;
+ALTER TABLE url_aliases ADD PRIMARY KEY (alias);
+
+DROP INDEX IF EXISTS urls_domain_short_code_key;
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+CREATE INDEX IF NOT EXISTS idx_urls_short_code ON urls(short_code);
+
+ALTER TABLE url_aliases DROP COLUMN IF EXISTS domain_id;
+ALTER TABLE urls DROP COLUMN IF EXISTS domain_id;
+
+DROP TABLE IF EXISTS domains;
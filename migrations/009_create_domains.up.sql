@@ -0,0 +1,20 @@
+-- Create domains table for branded short domains; the default domain from BASE_URL isn't stored
+CREATE TABLE IF NOT EXISTS domains (
+    id SERIAL PRIMARY KEY,
+    host VARCHAR(255) NOT NULL UNIQUE,
+    scheme VARCHAR(5) NOT NULL DEFAULT 'https' CHECK (scheme IN ('http', 'https')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Links and aliases belong to a domain; NULL is the default domain
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS domain_id INTEGER REFERENCES domains(id) ON DELETE RESTRICT;
+ALTER TABLE url_aliases ADD COLUMN IF NOT EXISTS domain_id INTEGER REFERENCES domains(id) ON DELETE RESTRICT;
+
+-- Short codes and aliases are unique per domain
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+DROP INDEX IF EXISTS idx_urls_short_code;
+CREATE UNIQUE INDEX IF NOT EXISTS urls_domain_short_code_key ON urls ((COALESCE(domain_id, 0)), short_code);
+
+ALTER TABLE url_aliases DROP CONSTRAINT IF EXISTS url_aliases_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS url_aliases_domain_alias_key ON url_aliases ((COALESCE(domain_id, 0)), alias);
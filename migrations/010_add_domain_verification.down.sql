@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS acme_cache;
+
+ALTER TABLE domains DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE domains DROP COLUMN IF EXISTS verification_token;
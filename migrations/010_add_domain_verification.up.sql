@@ -0,0 +1,11 @@
+-- Customer domains stay pending until ownership is verified; domains registered before this are trusted
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS verification_token VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP WITH TIME ZONE;
+UPDATE domains SET verified_at = created_at WHERE verified_at IS NULL;
+
+-- ACME account keys and certificates, shared by all replicas
+CREATE TABLE IF NOT EXISTS acme_cache (
+    key VARCHAR(255) PRIMARY KEY,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
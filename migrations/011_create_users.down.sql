@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_urls_user_id;
+ALTER TABLE urls DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
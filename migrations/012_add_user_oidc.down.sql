@@ -0,0 +1,7 @@
+DELETE FROM users WHERE password_hash IS NULL;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
+
+DROP INDEX IF EXISTS users_oidc_identity_key;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_issuer;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,8 @@
+-- Roles, and identities of users who sign in through an OpenID Connect provider
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_identity_key ON users (oidc_issuer, oidc_subject);
+
+-- Single sign-on users have no password
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
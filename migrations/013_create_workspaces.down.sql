@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_urls_workspace_id;
+ALTER TABLE urls DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
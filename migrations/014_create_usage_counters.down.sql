@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS workspace_quotas;
+DROP TABLE IF EXISTS usage_counters;
@@ -0,0 +1,17 @@
+-- Monthly usage per quota owner, a workspace or a user
+CREATE TABLE IF NOT EXISTS usage_counters (
+    owner_type VARCHAR(20) NOT NULL,
+    owner_id INTEGER NOT NULL,
+    period DATE NOT NULL,
+    links_created BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (owner_type, owner_id, period)
+);
+
+-- Limits set for individual workspaces; NULL uses the configured default
+CREATE TABLE IF NOT EXISTS workspace_quotas (
+    workspace_id INTEGER PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    links_per_month BIGINT,
+    active_links BIGINT,
+    clicks_per_month BIGINT
+);
@@ -0,0 +1,22 @@
+-- Every edit to a link's destination, primary alias, expiry or preview setting, numbered from 1
+CREATE TABLE IF NOT EXISTS url_versions (
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    original_url TEXT NOT NULL,
+    custom_alias VARCHAR(50),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    always_preview BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (url_id, version)
+);
+
+-- Existing links start their history at their current state
+INSERT INTO url_versions (url_id, version, original_url, custom_alias, expires_at, always_preview, created_by, created_at)
+SELECT urls.id, 1, urls.original_url,
+    (SELECT url_aliases.alias FROM url_aliases
+        WHERE url_aliases.url_id = urls.id AND url_aliases.retired_at IS NULL
+        ORDER BY url_aliases.created_at, url_aliases.alias LIMIT 1),
+    urls.expires_at, urls.always_preview, urls.user_id, urls.created_at
+FROM urls
+ON CONFLICT DO NOTHING;
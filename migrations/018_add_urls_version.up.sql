@@ -0,0 +1,6 @@
+-- A link's current version number in its history, served as its ETag
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+UPDATE urls SET version = latest.version
+FROM (SELECT url_id, MAX(version) AS version FROM url_versions GROUP BY url_id) latest
+WHERE urls.id = latest.url_id;
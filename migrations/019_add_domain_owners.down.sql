@@ -0,0 +1,2 @@
+ALTER TABLE domains DROP COLUMN IF EXISTS owner_workspace_id;
+ALTER TABLE domains DROP COLUMN IF EXISTS owner_user_id;
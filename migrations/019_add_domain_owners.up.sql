@@ -0,0 +1,4 @@
+-- Customer domains belong to the user or workspace that registered them; domains without
+-- an owner were added by an admin and are open to everyone
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS owner_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS owner_workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
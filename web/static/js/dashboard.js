@@ -0,0 +1,140 @@
+document.addEventListener('DOMContentLoaded', function() {
+    // Sent with every state-changing request; the server rejects them without it
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+
+    // Read the error message from a failed API response
+    async function errorMessage(response) {
+        const text = await response.text();
+        try {
+            const data = JSON.parse(text);
+            return data.message || text;
+        } catch (e) {
+            return text || `Error: ${response.status} ${response.statusText}`;
+        }
+    }
+
+    // Join the workspace an invitation link is for, then show its links
+    const invitation = document.querySelector('.invitation-banner');
+    const acceptBtn = invitation && invitation.querySelector('.invitation-accept');
+    if (acceptBtn) {
+        acceptBtn.addEventListener('click', async function() {
+            acceptBtn.disabled = true;
+            try {
+                const response = await fetch('/api/v1/invitations/accept', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                        'X-CSRF-Token': csrfToken
+                    },
+                    body: JSON.stringify({ token: invitation.dataset.token })
+                });
+                if (!response.ok) {
+                    throw new Error(await errorMessage(response));
+                }
+                const data = await response.json();
+                window.location.href = `/dashboard?workspace_id=${data.workspace_id}`;
+            } catch (error) {
+                alert(`Failed to accept invitation: ${error.message}`);
+                acceptBtn.disabled = false;
+            }
+        });
+    }
+
+    document.querySelectorAll('.link-card').forEach(card => {
+        const id = card.dataset.id;
+        const form = card.querySelector('.link-edit-form');
+        const deleteBtn = card.querySelector('.link-delete');
+
+        // Viewers get the stats without the edit form
+        if (!form) {
+            return;
+        }
+
+        // A new expiry and never expiring rule each other out
+        form.elements.expires_in.addEventListener('input', function() {
+            if (this.value) {
+                form.elements.never_expires.checked = false;
+            }
+        });
+        form.elements.never_expires.addEventListener('change', function() {
+            if (this.checked) {
+                form.elements.expires_in.value = '';
+            }
+        });
+
+        // Save destination, alias and expiry changes
+        form.addEventListener('submit', async function(e) {
+            e.preventDefault();
+
+            const alias = form.elements.custom_alias.value.trim();
+            if (alias && (alias.length < 3 || alias.length > 50)) {
+                alert("Custom alias must be between 3 and 50 characters");
+                return;
+            }
+
+            // A merge patch: an empty alias is sent as null to remove it, and so is the expiry
+            // of a link that should never expire
+            const payload = {
+                original_url: form.elements.original_url.value,
+                custom_alias: alias || null
+            };
+            const expiresIn = parseInt(form.elements.expires_in.value, 10);
+            if (expiresIn > 0) {
+                payload.expires_at = new Date(Date.now() + expiresIn * 24 * 60 * 60 * 1000).toISOString();
+            } else if (form.elements.never_expires.checked) {
+                payload.expires_at = null;
+            }
+
+            const submitBtn = form.querySelector('button[type="submit"]');
+            const originalBtnText = submitBtn.innerHTML;
+            submitBtn.innerHTML = '<i class="fas fa-spinner fa-spin"></i> Saving...';
+            submitBtn.disabled = true;
+
+            try {
+                // Only save over the version shown on the page
+                const response = await fetch(`/api/v1/urls/${id}`, {
+                    method: 'PATCH',
+                    headers: {
+                        'Content-Type': 'application/merge-patch+json',
+                        'If-Match': `"${card.dataset.version}"`,
+                        'X-CSRF-Token': csrfToken
+                    },
+                    body: JSON.stringify(payload)
+                });
+                if (response.status === 412) {
+                    throw new Error('This link was changed somewhere else. Reload the page to see the latest version.');
+                }
+                if (!response.ok) {
+                    throw new Error(await errorMessage(response));
+                }
+                window.location.reload();
+            } catch (error) {
+                alert(`Failed to update link: ${error.message}`);
+                submitBtn.innerHTML = originalBtnText;
+                submitBtn.disabled = false;
+            }
+        });
+
+        // Delete the link after confirmation
+        deleteBtn.addEventListener('click', async function() {
+            if (!confirm('Move this link to the trash? It will stop redirecting immediately.')) {
+                return;
+            }
+
+            try {
+                const response = await fetch(`/api/v1/urls/${id}`, {
+                    method: 'DELETE',
+                    headers: {
+                        'X-CSRF-Token': csrfToken
+                    }
+                });
+                if (!response.ok) {
+                    throw new Error(await errorMessage(response));
+                }
+                card.remove();
+            } catch (error) {
+                alert(`Failed to delete link: ${error.message}`);
+            }
+        });
+    });
+});
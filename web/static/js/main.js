@@ -1,4 +1,6 @@
 document.addEventListener('DOMContentLoaded', function() {
+    // Sent with every state-changing request; the server rejects them without it
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
     const shortenForm = document.getElementById('shortenForm');
     const result = document.getElementById('result');
     const shortUrlInput = document.getElementById('shortUrl');
@@ -31,10 +33,11 @@ document.addEventListener('DOMContentLoaded', function() {
         const originalUrl = document.getElementById('originalUrl').value;
         const customAlias = document.getElementById('customAlias').value;
         const expiresIn = parseInt(document.getElementById('expiresIn').value, 10);
+        const alwaysPreview = document.getElementById('alwaysPreview').checked;
         
         // Validate custom alias length
-        if (customAlias && (customAlias.length < 3 || customAlias.length > 10)) {
-            alert("Custom alias must be between 3 and 10 characters");
+        if (customAlias && (customAlias.length < 3 || customAlias.length > 50)) {
+            alert("Custom alias must be between 3 and 50 characters");
             return;
         }
         
@@ -48,19 +51,27 @@ document.addEventListener('DOMContentLoaded', function() {
             // Prepare request payload
             const payload = {
                 original_url: originalUrl,
-                expires_in: expiresIn
+                expires_in: expiresIn,
+                always_preview: alwaysPreview
             };
             
             // Add custom alias if provided
             if (customAlias) {
                 payload.custom_alias = customAlias;
             }
+
+            // Shorten into a workspace when one is picked
+            const workspaceSelect = document.getElementById('workspaceId');
+            if (workspaceSelect && workspaceSelect.value) {
+                payload.workspace_id = parseInt(workspaceSelect.value, 10);
+            }
             
             // Send request to API
             const response = await fetch('/api/v1/urls', {
                 method: 'POST',
                 headers: {
-                    'Content-Type': 'application/json'
+                    'Content-Type': 'application/json',
+                    'X-CSRF-Token': csrfToken
                 },
                 body: JSON.stringify(payload)
             });
@@ -0,0 +1,25 @@
+// Package web embeds the page templates and static assets into the binary.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+//go:embed static
+var static embed.FS
+
+// Templates returns the page templates, rooted at the templates directory
+func Templates() fs.FS {
+	sub, _ := fs.Sub(templates, "templates")
+	return sub
+}
+
+// Static returns the static assets, rooted at the static directory
+func Static() fs.FS {
+	sub, _ := fs.Sub(static, "static")
+	return sub
+}